@@ -0,0 +1,360 @@
+package xobj
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/worldiety/jsonml"
+)
+
+// A BodyEncoder converts an Obj into a request body, see #RequestBuilder.EncodedBody().
+type BodyEncoder interface {
+	// ContentType returns the value of the Content-Type header
+	ContentType() string
+
+	// Encode returns the body for the object
+	Encode(obj Obj) ([]byte, error)
+}
+
+type bodyEncoder struct {
+	contentType string
+	encode      func(obj Obj) ([]byte, error)
+}
+
+func (e bodyEncoder) ContentType() string {
+	return e.contentType
+}
+
+func (e bodyEncoder) Encode(obj Obj) ([]byte, error) {
+	return e.encode(obj)
+}
+
+// NewBodyEncoder creates a BodyEncoder from a content type and an ordinary function.
+func NewBodyEncoder(contentType string, encode func(obj Obj) ([]byte, error)) BodyEncoder {
+	return bodyEncoder{contentType: contentType, encode: encode}
+}
+
+// bodyEncoders contain all registered formats by name
+var bodyEncoders = map[string]BodyEncoder{}
+
+// RegisterBodyEncoder adds or replaces a format for #RequestBuilder.EncodedBody(). The formats json, form and
+// xml are registered by default. Like #RegisterParser(), this is not thread safe, so do it at #init() time.
+func RegisterBodyEncoder(name string, encoder BodyEncoder) {
+	bodyEncoders[name] = encoder
+}
+
+func init() {
+	RegisterBodyEncoder("json", NewBodyEncoder("application/json", func(obj Obj) ([]byte, error) {
+		return []byte(obj.String()), nil
+	}))
+
+	RegisterBodyEncoder("form", NewBodyEncoder("application/x-www-form-urlencoded", func(obj Obj) ([]byte, error) {
+		values := url.Values{}
+		addFormValues(values, "", obj)
+		return []byte(values.Encode()), nil
+	}))
+
+	RegisterBodyEncoder("xml", NewBodyEncoder("application/xml", func(obj Obj) ([]byte, error) {
+		str, err := ToXML(obj)
+		return []byte(str), err
+	}))
+}
+
+// addFormValues adds primitives as they are, arrays as repeated keys and nested objects like a[b]=c
+func addFormValues(values url.Values, key string, v interface{}) {
+	if obj, ok := toObj(v); ok && !isNil(v) {
+		for _, k := range sortedKeys(obj) {
+			name := k
+			if key != "" {
+				name = key + "[" + k + "]"
+			}
+			addFormValues(values, name, obj.Get(k))
+		}
+		return
+	}
+	if arr, ok := toArr(v); ok && !isNil(v) {
+		for i := 0; i < arr.Size(); i++ {
+			addFormValues(values, key, arr.Get(i))
+		}
+		return
+	}
+	if !isNil(v) {
+		values.Add(key, ToString(v))
+	}
+}
+
+// ToXML writes the JsonML array of the xml member as xml, which is the inverse of #Parse() for xml documents.
+// Documents which have been parsed are written by the jsonml package, which also restores the namespaces. Its
+// writer only supports its own nodes, so documents which have been created or extended otherwise are
+// written by an equivalent writer, which writes tag and attribute names as they are.
+func ToXML(obj Obj) (string, error) {
+	v := obj.Get("xml")
+	if slice, ok := v.([]interface{}); ok && isParsedJSONML(slice) {
+		return jsonml.ToXML(slice)
+	}
+	if _, ok := toArr(v); !ok || isNil(v) {
+		return "", withPath(Path{"xml"}, typeMismatch(v, "array"))
+	}
+
+	sb := &strings.Builder{}
+	enc := xml.NewEncoder(sb)
+	if err := writeJSONML(enc, v); err != nil {
+		return "", err
+	}
+	if err := enc.Flush(); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// isParsedJSONML returns true, if all child elements are the nodes of the jsonml package and all attributes
+// are plain maps, which is what #Parse() returns for xml documents
+func isParsedJSONML(node []interface{}) bool {
+	for _, v := range node {
+		switch t := v.(type) {
+		case wrapper:
+			if !isParsedJSONML(t.Unwrap()) {
+				return false
+			}
+		case map[string]interface{}:
+		default:
+			if isContainer(v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// writeJSONML writes an element node: [tag name, optional attributes, children...]
+func writeJSONML(enc *xml.Encoder, v interface{}) error {
+	arr, _ := toArr(v)
+	if arr.Size() == 0 {
+		return fmt.Errorf("empty JsonML element")
+	}
+	start := xml.StartElement{Name: xml.Name{Local: ToString(arr.Get(0))}}
+	children := elements(arr)[1:]
+	if len(children) > 0 {
+		if attrs, ok := toObj(children[0]); ok && !isNil(children[0]) {
+			for _, k := range sortedKeys(attrs) {
+				start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: k}, Value: ToString(attrs.Get(k))})
+			}
+			children = children[1:]
+		}
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, child := range children {
+		if _, ok := toArr(child); ok && !isNil(child) {
+			if err := writeJSONML(enc, child); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := enc.EncodeToken(xml.CharData(ToString(child))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+//=
+
+// EncodedBody encodes the obj with a registered BodyEncoder, see #RegisterBodyEncoder(), and sets the content
+// type accordingly.
+func (r *RequestBuilder) EncodedBody(format string, obj Obj) *RequestBuilder {
+	encoder, ok := bodyEncoders[format]
+	if !ok {
+		r.err = fmt.Errorf("unknown body format '%s'", format)
+		return r
+	}
+	data, err := encoder.Encode(obj)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	return r.BytesBody(encoder.ContentType(), data)
+}
+
+// FormBody encodes the obj as application/x-www-form-urlencoded. Arrays are written as repeated keys and
+// nested objects like a[b]=c.
+func (r *RequestBuilder) FormBody(obj Obj) *RequestBuilder {
+	return r.EncodedBody("form", obj)
+}
+
+// XMLBody encodes the obj as application/xml, see #ToXML().
+func (r *RequestBuilder) XMLBody(obj Obj) *RequestBuilder {
+	return r.EncodedBody("xml", obj)
+}
+
+// BytesBody sends the data with the given content type. The body can be sent again by retries.
+func (r *RequestBuilder) BytesBody(contentType string, data []byte) *RequestBuilder {
+	r.header.Set("Content-Type", contentType)
+	return r.BodyFunc(int64(len(data)), func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	})
+}
+
+// BufferedBody reads the reader completely, so that the body can be sent again by retries.
+func (r *RequestBuilder) BufferedBody(reader io.Reader) *RequestBuilder {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	return r.BodyFunc(int64(len(data)), func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	})
+}
+
+// BodyFunc sets a function which opens the body for each attempt, e.g. by opening a file again. A negative
+// content length means unknown and the body is sent in chunks.
+func (r *RequestBuilder) BodyFunc(contentLength int64, open func() (io.ReadCloser, error)) *RequestBuilder {
+	r.body = nil
+	r.openBody = open
+	r.contentLength = contentLength
+	return r
+}
+
+//=
+
+// A FilePart is a file within a multipart body.
+type FilePart struct {
+	// Field is the name of the form field
+	Field string
+
+	// FileName and ContentType of the part, the default content type is application/octet-stream
+	FileName    string
+	ContentType string
+
+	// Size is the length of the content, zero or negative if unknown
+	Size int64
+
+	// Open returns the content and is invoked for each attempt
+	Open func() (io.ReadCloser, error)
+}
+
+// NewFilePart creates a part whose content is read from the reader once and kept in memory.
+func NewFilePart(field, fileName, contentType string, reader io.Reader) (*FilePart, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePart{Field: field, FileName: fileName, ContentType: contentType, Size: int64(len(data)), Open: func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}}, nil
+}
+
+// NewFilePartFS creates a part which opens the file for each attempt.
+func NewFilePartFS(field string, fsys fs.FS, name, contentType string) (*FilePart, error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePart{Field: field, FileName: path.Base(name), ContentType: contentType, Size: info.Size(), Open: func() (io.ReadCloser, error) {
+		return fsys.Open(name)
+	}}, nil
+}
+
+// MultipartBody encodes the members of the obj as form fields, like #RequestBuilder.FormBody(), followed by the
+// files as multipart/form-data. The body is streamed and the files are opened again for each attempt. The
+// content length is only known, if the size of all files is known.
+func (r *RequestBuilder) MultipartBody(obj Obj, files ...*FilePart) *RequestBuilder {
+	values := url.Values{}
+	if obj != nil {
+		addFormValues(values, "", obj)
+	}
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+
+	write := func(w io.Writer, content func(part *FilePart) (io.ReadCloser, error)) error {
+		mw := multipart.NewWriter(w)
+		if err := mw.SetBoundary(boundary); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			for _, v := range values[k] {
+				if err := mw.WriteField(k, v); err != nil {
+					return err
+				}
+			}
+		}
+		for _, f := range files {
+			contentType := f.ContentType
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(f.Field), quoteEscaper.Replace(f.FileName)))
+			header.Set("Content-Type", contentType)
+			pw, err := mw.CreatePart(header)
+			if err != nil {
+				return err
+			}
+			rc, err := content(f)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(pw, rc)
+			silentClose(rc)
+			if err != nil {
+				return err
+			}
+		}
+		return mw.Close()
+	}
+
+	// the length of the envelope plus the sizes of the files
+	size := int64(-1)
+	counter := &countingWriter{}
+	empty := func(part *FilePart) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	if err := write(counter, empty); err == nil {
+		size = counter.n
+		for _, f := range files {
+			if f.Size <= 0 {
+				size = -1
+				break
+			}
+			size += f.Size
+		}
+	}
+
+	r.header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+	return r.BodyFunc(size, func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(write(pw, func(part *FilePart) (io.ReadCloser, error) {
+				return part.Open()
+			}))
+		}()
+		return pr, nil
+	})
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
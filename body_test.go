@@ -0,0 +1,165 @@
+package xobj
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// echoServer answers with the content type, length and body of each request, failing the first n requests
+func echoServer(t *testing.T, failures int) func() *RequestBuilder {
+	count := 0
+	srv := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		count++
+		data, _ := io.ReadAll(req.Body)
+		if count <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		obj := NewObj().
+			PutString("type", req.Header.Get("Content-Type")).
+			PutInt64("length", req.ContentLength).
+			PutString("body", string(data)).
+			PutInt64("count", int64(count))
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/") {
+			req.Body = io.NopCloser(strings.NewReader(string(data)))
+			if err := req.ParseMultipartForm(1 << 20); err != nil {
+				t.Error(err)
+			}
+			for k, v := range req.MultipartForm.Value {
+				obj.PutString(k, strings.Join(v, ","))
+			}
+			for k, files := range req.MultipartForm.File {
+				f, _ := files[0].Open()
+				content, _ := io.ReadAll(f)
+				obj.PutString(k, files[0].Filename+":"+files[0].Header.Get("Content-Type")+":"+string(content))
+			}
+		}
+		_, _ = w.Write([]byte(obj.String()))
+	}))
+
+	return func() *RequestBuilder {
+		policy := DefaultRetryPolicy(3)
+		policy.BaseDelay = 0
+		return NewRequest().URL(srv.URL).RetryPolicy(policy)
+	}
+}
+
+func TestRequestBuilder_Bodies(t *testing.T) {
+	newRequest := echoServer(t, 0)
+	obj := parseObj(t, `{"name":"a b","tags":[1,2],"nested":{"x":true},"skip":null}`)
+
+	res, _, err := newRequest().FormBody(obj).Post()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "name=a+b&nested%5Bx%5D=true&tags=1&tags=2"
+	if Opt[string](res, "type", "") != "application/x-www-form-urlencoded" || Opt[string](res, "body", "") != expected ||
+		Opt[int64](res, "length", 0) != int64(len(expected)) {
+		t.Fatal("unexpected", res)
+	}
+
+	res, _, err = newRequest().JSONBody(parseObj(t, `{"a":1}`)).Post()
+	if err != nil || Opt[string](res, "type", "") != "application/json" || Opt[string](res, "body", "") != `{"a":1}` {
+		t.Fatal("unexpected", res, err)
+	}
+
+	res, _, err = newRequest().XMLBody(parseObj(t, `{"xml":["root",{"id":"1"},["child","a<b"],"text"]}`)).Post()
+	if err != nil || Opt[string](res, "type", "") != "application/xml" || Opt[string](res, "body", "") != `<root id="1"><child>a&lt;b</child>text</root>` {
+		t.Fatal("unexpected", res, err)
+	}
+
+	if _, _, err = newRequest().EncodedBody("unknown", obj).Post(); err == nil {
+		t.Fatal("expected error")
+	}
+
+	RegisterBodyEncoder("test", NewBodyEncoder("text/plain", func(obj Obj) ([]byte, error) {
+		return []byte(strings.Join(sortedKeys(obj), ",")), nil
+	}))
+	res, _, err = newRequest().EncodedBody("test", obj).Post()
+	if err != nil || Opt[string](res, "type", "") != "text/plain" || Opt[string](res, "body", "") != "name,nested,skip,tags" {
+		t.Fatal("unexpected", res, err)
+	}
+}
+
+func TestRequestBuilder_MultipartBody(t *testing.T) {
+	newRequest := echoServer(t, 1)
+	fsys := fstest.MapFS{"dir/report.csv": {Data: []byte("a,b\n1,2\n")}}
+
+	memory, err := NewFilePart("upload", "hello.txt", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := NewFilePartFS("report", fsys, "dir/report.csv", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first attempt fails, so that the body is sent twice
+	res, status, err := newRequest().MultipartBody(parseObj(t, `{"title":"x","ids":[1,2]}`), memory, file).Put()
+	if err != nil || status != 200 || Opt[int64](res, "count", 0) != 2 {
+		t.Fatal("unexpected", res, status, err)
+	}
+	if !strings.HasPrefix(Opt[string](res, "type", ""), "multipart/form-data; boundary=") ||
+		Opt[int64](res, "length", 0) != int64(len(Opt[string](res, "body", ""))) {
+		t.Fatal("unexpected", res)
+	}
+	if Opt[string](res, "title", "") != "x" || Opt[string](res, "ids", "") != "1,2" ||
+		Opt[string](res, "upload", "") != "hello.txt:text/plain:hello" ||
+		Opt[string](res, "report", "") != "report.csv:application/octet-stream:a,b\n1,2\n" {
+		t.Fatal("unexpected", res)
+	}
+
+	// unknown sizes are sent in chunks
+	stream := &FilePart{Field: "report", FileName: "report.csv", Open: file.Open}
+	res, _, err = newRequest().MultipartBody(nil, stream).Put()
+	if err != nil || Opt[int64](res, "length", 0) != -1 {
+		t.Fatal("unexpected", res, err)
+	}
+}
+
+func TestToXML(t *testing.T) {
+	doc, err := Parse([]byte(`<a x="1"><b>text</b><c/></a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	str, err := ToXML(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Parse([]byte(str))
+	if err != nil || !Equal(doc, again) {
+		t.Fatal("unexpected", str, err)
+	}
+
+	if _, err := ToXML(parseObj(t, `{"a":1}`)); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestToXML_Namespaces(t *testing.T) {
+	src := `<a:root xmlns:a="urn:x"><a:child id="1">text</a:child><b>2</b></a:root>`
+	doc, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isParsedJSONML(doc.Get("xml").([]interface{})) {
+		t.Fatal("expected the jsonml writer")
+	}
+	str, err := ToXML(doc)
+	if err != nil || str != src {
+		t.Fatal("unexpected", str, err)
+	}
+
+	// the same document as plain json is written by the fallback
+	plain := parseObj(t, doc.String())
+	if isParsedJSONML(plain.Get("xml").([]interface{})) {
+		t.Fatal("expected the fallback writer")
+	}
+	str, err = ToXML(plain)
+	if err != nil || str != src {
+		t.Fatal("unexpected", str, err)
+	}
+}
@@ -0,0 +1,58 @@
+package xobj
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// base64Encodings are tried in order when decoding a string into bytes
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.URLEncoding,
+	base64.RawStdEncoding,
+	base64.RawURLEncoding,
+}
+
+// asBytes returns a copy of byte slices and decodes strings. A string with a 0x prefix is decoded as hex.
+// Anything else is decoded as standard or url-safe base64, with or without padding, which is also what
+// #Object.PutBytes() and the json package emit for byte slices. Hex without a prefix is not guessed,
+// because e.g. "1234" is valid base64 as well and would not survive a round trip.
+func asBytes(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case []byte:
+		return copyBytes(t), nil
+	case string:
+		b, err := decodeBytes(t)
+		if err != nil {
+			return nil, conversionFailed(v, "bytes", err)
+		}
+		return b, nil
+	}
+	return nil, typeMismatch(v, "bytes")
+}
+
+func decodeBytes(str string) ([]byte, error) {
+	str = strings.TrimSpace(str)
+	if strings.HasPrefix(str, "0x") || strings.HasPrefix(str, "0X") {
+		return hex.DecodeString(str[2:])
+	}
+
+	for _, enc := range base64Encodings {
+		if b, err := enc.DecodeString(str); err == nil {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("'%s' is neither base64 nor hex", str)
+}
+
+// copyBytes avoids that the caller and the document share the same backing array
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	res := make([]byte, len(b))
+	copy(res, b)
+	return res
+}
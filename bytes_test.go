@@ -0,0 +1,74 @@
+package xobj
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestObject_AsBytes(t *testing.T) {
+	data := []byte{0, 1, 2, 0xfb, 0xff, 0xfe, 42}
+	src := NewObj().PutBytes("data", data)
+	data[0] = 99
+	if v, err := src.AsBytes("data"); err != nil || v[0] != 0 {
+		t.Fatal("expected a copy", v, err)
+	}
+
+	obj, err := Parse([]byte(src.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := obj.AsBytes("data"); err != nil || !bytes.Equal(v, []byte{0, 1, 2, 0xfb, 0xff, 0xfe, 42}) {
+		t.Fatal("unexpected", v, err)
+	}
+
+	v, _ := obj.AsBytes("data")
+	v[0] = 99
+	if v, _ := obj.AsBytes("data"); v[0] != 0 {
+		t.Fatal("expected a copy", v)
+	}
+
+	obj, err = Parse([]byte(`{"hex":"0xCAFE","base64":"cafe","url":"-_8","std":"+/8=","broken":"#"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := obj.AsBytes("hex"); err != nil || !bytes.Equal(v, []byte{0xca, 0xfe}) {
+		t.Fatal("unexpected", v, err)
+	}
+	if v, err := obj.AsBytes("base64"); err != nil || !bytes.Equal(v, []byte{0x71, 0xa7, 0xde}) {
+		t.Fatal("unexpected", v, err)
+	}
+	for _, key := range []string{"url", "std"} {
+		if v, err := obj.AsBytes(key); err != nil || !bytes.Equal(v, []byte{0xfb, 0xff}) {
+			t.Fatal("unexpected", key, v, err)
+		}
+	}
+	if v := obj.OptBytes("broken", nil); v != nil {
+		t.Fatal("unexpected", v)
+	}
+}
+
+func TestObject_PutBytesRoundTrip(t *testing.T) {
+	// base64 of these bytes is 1234, which looks like hex as well
+	data := []byte{0xd7, 0x6d, 0xf8}
+	src := NewObj().PutBytes("d", data)
+	if src.String() != `{"d":"1234"}` {
+		t.Fatal("unexpected", src.String())
+	}
+	obj, err := Parse([]byte(src.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := obj.AsBytes("d"); err != nil || !bytes.Equal(v, data) {
+		t.Fatal("unexpected", v, err)
+	}
+}
+
+func TestArray_AddBytes(t *testing.T) {
+	arr := NewArr().AddBytes([]byte("hello"))
+	if v, err := arr.AsString(0); err != nil || v != "aGVsbG8=" {
+		t.Fatal("unexpected", v, err)
+	}
+	if arr.String() != `["aGVsbG8="]` {
+		t.Fatal("unexpected", arr.String())
+	}
+}
@@ -0,0 +1,258 @@
+package xobj
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Canonical returns the serialization of the object according to the JSON Canonicalization Scheme (JCS),
+// see RFC 8785. Keys are sorted by their UTF-16 code units, numbers are formatted like ECMAScript does and
+// strings use the minimal escaping. Equal documents (see #Equal()) have the same bytes, independent of the
+// key order, the numeric types or wrapped and raw containers.
+//
+// All numbers are represented as IEEE 754 doubles, so integers beyond 2^53 lose precision. NaN,
+// infinity and strings which are not valid UTF-8 cannot be represented and cause an error.
+func Canonical(obj Obj) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := WriteCanonical(buf, obj); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteCanonical writes the canonical serialization (see #Canonical()) into the writer.
+func WriteCanonical(w io.Writer, obj Obj) error {
+	bw := bufio.NewWriter(w)
+	if err := writeCanonical(bw, Path{}, obj); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ContentHash returns the SHA-256 of the canonical serialization.
+func ContentHash(obj Obj) ([sha256.Size]byte, error) {
+	h := sha256.New()
+	var res [sha256.Size]byte
+	if err := WriteCanonical(h, obj); err != nil {
+		return res, err
+	}
+	copy(res[:], h.Sum(nil))
+	return res, nil
+}
+
+// ContentID returns an identifier like sha256:<hex>, which is suited as a key for content addressed storage.
+func ContentID(obj Obj) (string, error) {
+	sum, err := ContentHash(obj)
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// ETag returns a strong entity tag, including the quotes, which is derived from the content hash.
+func ETag(obj Obj) (string, error) {
+	sum, err := ContentHash(obj)
+	if err != nil {
+		return "", err
+	}
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:]) + `"`, nil
+}
+
+func writeCanonical(w *bufio.Writer, path Path, v interface{}) error {
+	if isNil(v) {
+		_, err := w.WriteString("null")
+		return err
+	}
+
+	if obj, ok := toObj(v); ok {
+		list := obj.Keys()
+		keys := make([]string, list.Size())
+		for i := range keys {
+			keys[i] = list.Get(i)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return lessUTF16(keys[i], keys[j])
+		})
+		w.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := writeCanonicalString(w, path.Child(k), k); err != nil {
+				return err
+			}
+			w.WriteByte(':')
+			if err := writeCanonical(w, path.Child(k), obj.Get(k)); err != nil {
+				return err
+			}
+		}
+		return w.WriteByte('}')
+	}
+
+	if arr, ok := toArr(v); ok {
+		w.WriteByte('[')
+		for i := 0; i < arr.Size(); i++ {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := writeCanonical(w, path.Child(i), arr.Get(i)); err != nil {
+				return err
+			}
+		}
+		return w.WriteByte(']')
+	}
+
+	if n, ok := toNumber(v); ok {
+		str, err := formatECMAScript(n.float())
+		if err != nil {
+			return withPath(path, err)
+		}
+		_, err = w.WriteString(str)
+		return err
+	}
+
+	switch t := v.(type) {
+	case bool:
+		_, err := w.WriteString(strconv.FormatBool(t))
+		return err
+	case string:
+		return writeCanonicalString(w, path, t)
+	case []byte:
+		return writeCanonicalString(w, path, base64.StdEncoding.EncodeToString(t))
+	}
+
+	// anything else, like structs, is normalized through the json package
+	data, err := json.Marshal(v)
+	if err != nil {
+		return withPath(path, conversionFailed(v, "json", err))
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return withPath(path, conversionFailed(v, "json", err))
+	}
+	return writeCanonical(w, path, generic)
+}
+
+// writeCanonicalString escapes only quotation mark, reverse solidus and control characters
+func writeCanonicalString(w *bufio.Writer, path Path, str string) error {
+	if !utf8.ValidString(str) {
+		return withPath(path, conversionFailed(str, "canonical json", fmt.Errorf("invalid UTF-8")))
+	}
+	w.WriteByte('"')
+	for _, r := range str {
+		switch r {
+		case '"':
+			w.WriteString(`\"`)
+		case '\\':
+			w.WriteString(`\\`)
+		case '\b':
+			w.WriteString(`\b`)
+		case '\f':
+			w.WriteString(`\f`)
+		case '\n':
+			w.WriteString(`\n`)
+		case '\r':
+			w.WriteString(`\r`)
+		case '\t':
+			w.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(w, `\u%04x`, r)
+				continue
+			}
+			w.WriteRune(r)
+		}
+	}
+	return w.WriteByte('"')
+}
+
+// lessUTF16 compares the strings by their UTF-16 code units, as required by RFC 8785. The runes are
+// compared in place, because the order only differs from the code point order for surrogate pairs.
+func lessUTF16(a, b string) bool {
+	for a != "" && b != "" {
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			ha, la := utf16Units(ra)
+			hb, lb := utf16Units(rb)
+			if ha != hb {
+				return ha < hb
+			}
+			return la < lb
+		}
+		a, b = a[na:], b[nb:]
+	}
+	return a == "" && b != ""
+}
+
+// utf16Units returns the code units of the rune, the second one is zero outside of surrogate pairs
+func utf16Units(r rune) (rune, rune) {
+	if r < 0x10000 {
+		return r, 0
+	}
+	return utf16.EncodeRune(r)
+}
+
+// formatECMAScript implements Number.prototype.toString for doubles, see ECMA-262 7.1.12.1
+func formatECMAScript(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("%v is not a valid json number", f)
+	}
+	if f == 0 {
+		return "0", nil
+	}
+
+	sb := &strings.Builder{}
+	if f < 0 {
+		sb.WriteByte('-')
+		f = -f
+	}
+
+	// the shortest representation which round trips, like d.ddde±x
+	mantissa, exp, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	e, err := strconv.Atoi(exp)
+	if err != nil {
+		return "", err
+	}
+	k := len(digits)
+	n := e + 1 // the value is 0.digits * 10^n
+
+	switch {
+	case k <= n && n <= 21:
+		sb.WriteString(digits)
+		sb.WriteString(strings.Repeat("0", n-k))
+	case 0 < n && n <= 21:
+		sb.WriteString(digits[:n])
+		sb.WriteByte('.')
+		sb.WriteString(digits[n:])
+	case -6 < n && n <= 0:
+		sb.WriteString("0.")
+		sb.WriteString(strings.Repeat("0", -n))
+		sb.WriteString(digits)
+	default:
+		sb.WriteByte(digits[0])
+		if k > 1 {
+			sb.WriteByte('.')
+			sb.WriteString(digits[1:])
+		}
+		sb.WriteByte('e')
+		if n-1 >= 0 {
+			sb.WriteByte('+')
+		}
+		sb.WriteString(strconv.Itoa(n - 1))
+	}
+	return sb.String(), nil
+}
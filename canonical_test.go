@@ -0,0 +1,112 @@
+package xobj
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestFormatECMAScript(t *testing.T) {
+	cases := map[float64]string{
+		0:                      "0",
+		math.Copysign(0, -1):   "0",
+		1:                      "1",
+		-1.5:                   "-1.5",
+		1e21:                   "1e+21",
+		1e23:                   "1e+23",
+		1e-7:                   "1e-7",
+		0.000001:               "0.000001",
+		333333333.3333333:      "333333333.3333333",
+		5e-324:                 "5e-324",
+		1.7976931348623157e308: "1.7976931348623157e+308",
+		9007199254740992:       "9007199254740992",
+		295147905179352830000:  "295147905179352830000",
+		4.50:                   "4.5",
+		2e-3:                   "0.002",
+		0.000001234:            "0.000001234",
+		-1.5e-10:               "-1.5e-10",
+	}
+	for f, expected := range cases {
+		str, err := formatECMAScript(f)
+		if err != nil || str != expected {
+			t.Fatal("unexpected", f, str, err)
+		}
+	}
+
+	if _, err := formatECMAScript(math.NaN()); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	// example from RFC 8785, section 3.2.2
+	obj, err := Parse([]byte(`{
+		"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+		"literals": [null, true, false]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Canonical(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`
+	if string(data) != expected {
+		t.Fatal("unexpected", string(data))
+	}
+
+	// sorting by UTF-16 code units, which places the surrogate pair before \ufb33
+	obj = NewObj().PutInt64("\u20ac", 1).PutInt64("\U0001F600", 2).PutInt64("\r", 3).PutInt64("1", 4).PutInt64("\u00f6", 5).PutInt64("\ufb33", 6)
+	data, err = Canonical(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{\"\\r\":3,\"1\":4,\"\u00f6\":5,\"\u20ac\":1,\"\U0001F600\":2,\"\ufb33\":6}" {
+		t.Fatal("unexpected", string(data))
+	}
+}
+
+func TestContentID(t *testing.T) {
+	a, err := Parse([]byte(`{"b":1,"a":[1.0,"x"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewObj().PutArray("a", NewArr().AddInt64(1).AddString("x")).PutInt64("b", 1)
+
+	idA, err := ContentID(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idB, err := ContentID(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idA != idB || !strings.HasPrefix(idA, "sha256:") || len(idA) != 7+64 {
+		t.Fatal("unexpected", idA, idB)
+	}
+
+	if _, err := ContentID(NewObj().PutFloat64("nan", math.NaN())); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestLessUTF16(t *testing.T) {
+	values := []string{"", "a", "ab", "b", "ö", "דּ", "￿", "\U0001F600", "\U0001F601", "\U00010000", "\U0001F600a"}
+	for _, a := range values {
+		for _, b := range values {
+			ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+			expected := slices.Compare(ua, ub) < 0
+			if lessUTF16(a, b) != expected {
+				t.Fatalf("unexpected %q < %q", a, b)
+			}
+		}
+	}
+	if n := testing.AllocsPerRun(10, func() { lessUTF16("\U0001F600b", "\U0001F600a") }); n != 0 {
+		t.Fatal("unexpected allocations", n)
+	}
+}
@@ -0,0 +1,243 @@
+package xobj
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ClientOptions configure the transport and the defaults of a Client.
+type ClientOptions struct {
+	// MaxIdleConns is the maximum amount of idle connections to all hosts, 0 means no limit
+	MaxIdleConns int
+
+	// MaxIdleConnsPerHost is the maximum amount of idle connections to a single host
+	MaxIdleConnsPerHost int
+
+	// MaxConnsPerHost limits the connections to a single host, including active ones. 0 means no limit.
+	MaxConnsPerHost int
+
+	// IdleConnTimeout closes idle connections after the given duration
+	IdleConnTimeout time.Duration
+
+	// KeepAlive is the interval of tcp keep-alive probes, negative values disable them
+	KeepAlive time.Duration
+
+	// TLSHandshakeTimeout limits the tls handshake of new connections
+	TLSHandshakeTimeout time.Duration
+
+	// DisableHTTP2 uses only HTTP/1.1
+	DisableHTTP2 bool
+
+	// TLSConfig is used for https connections, if not nil
+	TLSConfig *tls.Config
+
+	// Proxy returns the proxy for a request, the default uses the environment
+	Proxy func(req *http.Request) (*url.URL, error)
+
+	// BaseURL is the default of the created RequestBuilders, see #RequestBuilder.BaseURL()
+	BaseURL string
+
+	// DialTimeout, ResponseHeaderTimeout and Timeout are the defaults of the created RequestBuilders
+	DialTimeout           time.Duration
+	ResponseHeaderTimeout time.Duration
+	Timeout               time.Duration
+
+	// Retry is the default amount of attempts of the created RequestBuilders
+	Retry int
+}
+
+// DefaultClientOptions returns the options of the shared client which is used by #NewRequest().
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		KeepAlive:             30 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		Proxy:                 http.ProxyFromEnvironment,
+		DialTimeout:           30 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		Retry:                 3,
+	}
+}
+
+// A Client owns a transport, so that the connections are reused by all of its requests. It is safe for
+// concurrent use and should be kept for the lifetime of the application, instead of being created per
+// request. The timeouts of a RequestBuilder are applied per request and not to the shared transport.
+type Client struct {
+	opts      ClientOptions
+	transport *http.Transport
+	client    *http.Client
+}
+
+var defaultClient struct {
+	once   sync.Once
+	client *Client
+}
+
+// DefaultClient returns the shared client, which is used by #NewRequest().
+func DefaultClient() *Client {
+	defaultClient.once.Do(func() {
+		defaultClient.client = NewClient(DefaultClientOptions())
+	})
+	return defaultClient.client
+}
+
+// NewClient creates a client with its own connection pool.
+func NewClient(opts ClientOptions) *Client {
+	dialer := &net.Dialer{KeepAlive: opts.KeepAlive}
+	transport := &http.Transport{
+		Proxy:               opts.Proxy,
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        opts.MaxIdleConns,
+		MaxIdleConnsPerHost: opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     opts.MaxConnsPerHost,
+		IdleConnTimeout:     opts.IdleConnTimeout,
+		TLSHandshakeTimeout: opts.TLSHandshakeTimeout,
+		TLSClientConfig:     opts.TLSConfig,
+		ForceAttemptHTTP2:   !opts.DisableHTTP2,
+	}
+	if opts.DisableHTTP2 {
+		// a non-nil empty map disables the automatic upgrade
+		transport.TLSNextProto = map[string]func(authority string, c *tls.Conn) http.RoundTripper{}
+	}
+	if opts.Retry == 0 {
+		opts.Retry = 1
+	}
+	return &Client{opts: opts, transport: transport, client: &http.Client{Transport: transport}}
+}
+
+// NewRequest returns a RequestBuilder, which uses the connections of this client and its defaults.
+func (c *Client) NewRequest() *RequestBuilder {
+	r := &RequestBuilder{
+		client:                c,
+		retry:                 c.opts.Retry,
+		header:                http.Header{},
+		query:                 url.Values{},
+		responseHeaderTimeout: c.opts.ResponseHeaderTimeout,
+		dialTimeout:           c.opts.DialTimeout,
+		timeout:               c.opts.Timeout,
+	}
+	if c.opts.BaseURL != "" {
+		r.BaseURL(c.opts.BaseURL)
+	}
+	return r
+}
+
+// HTTPClient returns the underlying client, e.g. to use it with other libraries.
+func (c *Client) HTTPClient() *http.Client {
+	return c.client
+}
+
+// CloseIdleConnections closes all connections which are currently not in use.
+func (c *Client) CloseIdleConnections() {
+	c.transport.CloseIdleConnections()
+}
+
+//=
+
+// A TimeoutError is returned, if a phase of a request has exceeded its timeout.
+type TimeoutError struct {
+	// Phase is connect, tls handshake, response header or total
+	Phase    string
+	Duration time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return e.Phase + " timeout of " + e.Duration.String() + " exceeded"
+}
+
+// Timeout implements the net.Error convention.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// phaseTimer cancels the request, if a phase does not finish in time. A phase may occur several times, e.g.
+// if a connection attempt fails and the next address is dialed, so the timer is armed again on each start.
+type phaseTimer struct {
+	mutex   sync.Mutex
+	timeout time.Duration
+	cause   error
+	cancel  context.CancelCauseFunc
+	timer   *time.Timer
+	running bool
+	closed  bool
+}
+
+// start arms the timer, unless the phase is already running
+func (p *phaseTimer) start() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.timeout <= 0 || p.running || p.closed {
+		return
+	}
+	p.running = true
+	if p.timer == nil {
+		p.timer = time.AfterFunc(p.timeout, func() {
+			p.cancel(p.cause)
+		})
+		return
+	}
+	p.timer.Reset(p.timeout)
+}
+
+func (p *phaseTimer) stop() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.running = false
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+}
+
+// close stops the timer for good, so that late trace events cannot arm it again
+func (p *phaseTimer) close() {
+	p.stop()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.closed = true
+}
+
+// withPhaseTimeouts returns a context which is cancelled with a TimeoutError, if connecting, the tls handshake
+// or awaiting the response header takes too long. Connecting includes the name resolution, like the timeout
+// of a net.Dialer. The returned function stops the timers.
+func withPhaseTimeouts(ctx context.Context, dial, handshake, header time.Duration) (context.Context, context.CancelCauseFunc, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	dialTimer := &phaseTimer{timeout: dial, cancel: cancel, cause: &TimeoutError{Phase: "connect", Duration: dial}}
+	tlsTimer := &phaseTimer{timeout: handshake, cancel: cancel, cause: &TimeoutError{Phase: "tls handshake", Duration: handshake}}
+	headerTimer := &phaseTimer{timeout: header, cancel: cancel, cause: &TimeoutError{Phase: "response header", Duration: header}}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			dialTimer.start()
+		},
+		ConnectStart: func(network, addr string) {
+			dialTimer.start()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			dialTimer.stop()
+		},
+		TLSHandshakeStart: func() {
+			tlsTimer.start()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			tlsTimer.stop()
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			headerTimer.start()
+		},
+		GotFirstResponseByte: func() {
+			headerTimer.stop()
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), cancel, func() {
+		dialTimer.close()
+		tlsTimer.close()
+		headerTimer.close()
+	}
+}
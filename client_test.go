@@ -0,0 +1,75 @@
+package xobj
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	var conns int32
+	srv := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}), func(srv *http.Server) {
+		srv.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				atomic.AddInt32(&conns, 1)
+			}
+		}
+	})
+
+	client := NewClient(DefaultClientOptions())
+	defer client.CloseIdleConnections()
+
+	for i := 0; i < 5; i++ {
+		obj, status, err := client.NewRequest().URL(srv.URL).Get()
+		if err != nil || status != 200 || !Opt[bool](obj, "ok", false) {
+			t.Fatal("unexpected", obj, status, err)
+		}
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Fatal("expected a single connection", n)
+	}
+
+	// the timeout applies only to this request
+	_, _, err := client.NewRequest().URL(srv.URL).Path("slow").
+		ResponseHeaderTimeout(50 * time.Millisecond).RetryPolicy(NoRetry).Get()
+	var timeout *TimeoutError
+	if !errors.As(err, &timeout) || timeout.Phase != "response header" {
+		t.Fatal("unexpected", err)
+	}
+
+	_, status, err := client.NewRequest().URL(srv.URL).Path("slow").Get()
+	if err != nil || status != 200 {
+		t.Fatal("unexpected", status, err)
+	}
+}
+
+func TestPhaseTimer(t *testing.T) {
+	fired := make(chan error, 1)
+	p := &phaseTimer{timeout: 20 * time.Millisecond, cause: errors.New("timeout"), cancel: func(cause error) { fired <- cause }}
+
+	// a second connection attempt is limited again
+	p.start()
+	p.stop()
+	p.start()
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("timer not armed again")
+	}
+
+	p.close()
+	p.start()
+	select {
+	case err := <-fired:
+		t.Fatal("unexpected", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,349 @@
+package xobj
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// configLayer provides the values of a single source
+type configLayer struct {
+	apply func(c *Config) error
+}
+
+// A ConfigLoader merges configuration layers into a single Obj. Layers are applied in the order in which
+// they have been added, so later layers override earlier ones. The usual order is defaults, files,
+// environment variables and finally command line flags. Objects are merged per key, all other values
+// including arrays are replaced as a whole.
+type ConfigLoader struct {
+	layers     []configLayer
+	validators []func(cfg *Config) error
+}
+
+// NewConfigLoader creates a loader without any layers.
+func NewConfigLoader() *ConfigLoader {
+	return &ConfigLoader{}
+}
+
+// AddObj adds a layer with the given values, e.g. the defaults. The name is reported as source.
+func (l *ConfigLoader) AddObj(name string, obj Obj) *ConfigLoader {
+	l.layers = append(l.layers, configLayer{apply: func(c *Config) error {
+		return c.overlay(Path{}, obj, name)
+	}})
+	return l
+}
+
+// AddFile adds a layer which is parsed by #Parse(), so that all registered formats are supported. A missing
+// optional file is ignored.
+func (l *ConfigLoader) AddFile(name string, optional bool) *ConfigLoader {
+	return l.addFile(name, optional, os.ReadFile)
+}
+
+// AddFileFS is like #ConfigLoader.AddFile() but reads from the given file system.
+func (l *ConfigLoader) AddFileFS(fsys fs.FS, name string, optional bool) *ConfigLoader {
+	return l.addFile(name, optional, func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	})
+}
+
+func (l *ConfigLoader) addFile(name string, optional bool, read func(name string) ([]byte, error)) *ConfigLoader {
+	l.layers = append(l.layers, configLayer{apply: func(c *Config) error {
+		data, err := read(name)
+		if err != nil {
+			if optional && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		obj, err := Parse(data)
+		if err != nil {
+			return fmt.Errorf("cannot parse %s: %w", name, err)
+		}
+		return c.overlay(Path{}, obj, name)
+	}})
+	return l
+}
+
+// AddEnv adds a layer of all environment variables with the given prefix. The prefix and the following
+// underscore are removed and double underscores separate nested keys, so APP_DB__HOST becomes db.host.
+// Keys are matched case insensitive with existing keys, otherwise they are lower case. Numeric segments
+// address array elements, if the value is an array. See #Coerce() for the conversion of the values.
+func (l *ConfigLoader) AddEnv(prefix string) *ConfigLoader {
+	return l.AddEnvFrom(prefix, os.Environ())
+}
+
+// AddEnvFrom is like #ConfigLoader.AddEnv() but reads the given key=value pairs.
+func (l *ConfigLoader) AddEnvFrom(prefix string, environ []string) *ConfigLoader {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	l.layers = append(l.layers, configLayer{apply: func(c *Config) error {
+		sorted := append([]string(nil), environ...)
+		sort.Strings(sorted)
+		for _, kv := range sorted {
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok || !strings.HasPrefix(key, prefix) || len(key) == len(prefix) {
+				continue
+			}
+			path := c.envPath(strings.Split(key[len(prefix):], "__"))
+			if err := c.set(path, value, "env:"+key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}})
+	return l
+}
+
+// AddFlags adds a layer of all flags, which have been set explicitly. The flag names are paths (see
+// #ParsePath()), like -db.host=localhost. Typed flag values are used as is, others are coerced.
+func (l *ConfigLoader) AddFlags(flags *flag.FlagSet) *ConfigLoader {
+	l.layers = append(l.layers, configLayer{apply: func(c *Config) error {
+		var err error
+		flags.Visit(func(f *flag.Flag) {
+			if err != nil {
+				return
+			}
+			path, e := ParsePath(f.Name)
+			if e != nil {
+				err = fmt.Errorf("invalid flag name %s: %w", f.Name, e)
+				return
+			}
+			var value interface{} = f.Value.String()
+			if getter, ok := f.Value.(flag.Getter); ok {
+				if v := getter.Get(); v != nil {
+					if _, isStr := v.(string); !isStr {
+						value = v
+					}
+				}
+			}
+			err = c.set(path, value, "flag:"+f.Name)
+		})
+		return err
+	}})
+	return l
+}
+
+// AddValidator registers a hook, which is invoked after all layers have been applied. All validation errors
+// are returned together.
+func (l *ConfigLoader) AddValidator(fn func(cfg *Config) error) *ConfigLoader {
+	l.validators = append(l.validators, fn)
+	return l
+}
+
+// RequireKeys returns a validator, which fails for each path (see #ParsePath()) without a value.
+func RequireKeys(paths ...string) func(cfg *Config) error {
+	return func(cfg *Config) error {
+		var errs []error
+		for _, p := range paths {
+			path, err := ParsePath(p)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if v, err := Lookup(cfg, path); err != nil || v == nil {
+				errs = append(errs, fmt.Errorf("missing required configuration %s", p))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// Load applies all layers and validates the result.
+func (l *ConfigLoader) Load() (*Config, error) {
+	c := &Config{Obj: Object{}, sources: map[string]string{}}
+	for _, layer := range l.layers {
+		if err := layer.apply(c); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, v := range l.validators {
+		if err := v(c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+//=
+
+// A Config is the merged result of a ConfigLoader, which remembers the source of each value.
+type Config struct {
+	Obj
+	sources map[string]string
+}
+
+// Source returns the name of the layer which has supplied the value at the given path (see #ParsePath()),
+// like a file name, env:APP_DB__HOST or flag:db.host. Elements of arrays report the source of the array.
+// The empty string is returned for unknown paths.
+func (c *Config) Source(path string) string {
+	p, err := ParsePath(path)
+	if err != nil {
+		return ""
+	}
+	for i := len(p); i > 0; i-- {
+		if s, ok := c.sources[p[:i].String()]; ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// Sources returns the paths of all values with their source.
+func (c *Config) Sources() Object {
+	res := Object{}
+	for k, v := range c.sources {
+		res[k] = v
+	}
+	return res
+}
+
+// overlay merges objects per key and replaces all other values
+func (c *Config) overlay(path Path, v interface{}, source string) error {
+	if obj, ok := toObj(v); ok && !isNil(v) {
+		if len(path) > 0 {
+			if cur, err := Lookup(c.Obj, path); err != nil || !isObjValue(cur) {
+				if err := c.replace(path, Object{}, source); err != nil {
+					return err
+				}
+			}
+		}
+		for _, k := range sortedKeys(obj) {
+			if err := c.overlay(path.Child(k), obj.Get(k), source); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return c.replace(path, v, source)
+}
+
+func isObjValue(v interface{}) bool {
+	_, ok := toObj(v)
+	return ok && !isNil(v)
+}
+
+// replace stores a copy of the value and forgets the sources of the replaced subtree
+func (c *Config) replace(path Path, v interface{}, source string) error {
+	if err := SetPath[interface{}](c.Obj, path, syncValue(v)); err != nil {
+		return fmt.Errorf("cannot set %s from %s: %w", path, source, err)
+	}
+	prefix := path.String()
+	for k := range c.sources {
+		if k == prefix || strings.HasPrefix(k, prefix+".") || strings.HasPrefix(k, prefix+"[") {
+			delete(c.sources, k)
+		}
+	}
+	c.sources[prefix] = source
+	return nil
+}
+
+// set coerces strings to the type of the current value and replaces it
+func (c *Config) set(path Path, value interface{}, source string) error {
+	if str, ok := value.(string); ok {
+		cur, _ := Lookup(c.Obj, path)
+		v, err := Coerce(str, cur)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s from %s: %w", path, source, err)
+		}
+		value = v
+	}
+	return c.replace(path, value, source)
+}
+
+// envPath maps the segments of an environment variable to existing keys and indices
+func (c *Config) envPath(segments []string) Path {
+	var res Path
+	var cur interface{} = c.Obj
+	for _, seg := range segments {
+		var next interface{} = strings.ToLower(seg)
+		if obj, ok := toObj(cur); ok && !isNil(cur) {
+			for _, k := range sortedKeys(obj) {
+				if strings.EqualFold(k, seg) {
+					next = k
+					break
+				}
+			}
+		} else if _, ok := toArr(cur); ok && !isNil(cur) {
+			if idx, err := strconv.Atoi(seg); err == nil {
+				next = idx
+			}
+		}
+		res = append(res, next)
+		v, err := child(cur, next)
+		if err != nil {
+			v = nil
+		}
+		cur = v
+	}
+	return res
+}
+
+// Coerce converts a string from an environment variable or a flag into the type of the current value, using
+// the conversions of the typed accessors. Arrays accept json or comma separated values and objects accept
+// json. Without a current value, booleans and numbers are recognized and everything else stays a string.
+func Coerce(str string, current interface{}) (interface{}, error) {
+	switch {
+	case isNil(current):
+		return inferValue(str), nil
+	case isObjValue(current):
+		var obj Object
+		if err := json.Unmarshal([]byte(str), &obj); err != nil || obj == nil {
+			return nil, conversionFailed(str, "object", err)
+		}
+		return obj, nil
+	case isContainer(current):
+		if strings.HasPrefix(strings.TrimSpace(str), "[") {
+			var arr Array
+			if err := json.Unmarshal([]byte(str), &arr); err != nil || arr == nil {
+				return nil, conversionFailed(str, "array", err)
+			}
+			return &arr, nil
+		}
+		res := Array{}
+		if str != "" {
+			for _, s := range strings.Split(str, ",") {
+				res = append(res, inferValue(strings.TrimSpace(s)))
+			}
+		}
+		return &res, nil
+	}
+
+	if _, ok := current.(bool); ok {
+		return asBool(str)
+	}
+	if n, ok := toNumber(current); ok {
+		if n.isInt {
+			if i, err := asInt64(str); err == nil {
+				return i, nil
+			}
+		}
+		return asFloat64(str)
+	}
+	return str, nil
+}
+
+// inferValue recognizes booleans and numbers
+func inferValue(str string) interface{} {
+	if b, err := strconv.ParseBool(str); err == nil && (str == "true" || str == "false") {
+		return b
+	}
+	if i, err := strconv.ParseInt(str, 10, 64); err == nil && strconv.FormatInt(i, 10) == str {
+		return i
+	}
+	if f, err := strconv.ParseFloat(str, 64); err == nil && !strings.ContainsAny(str, "xXnN_") {
+		return f
+	}
+	return str
+}
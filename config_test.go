@@ -0,0 +1,127 @@
+package xobj
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestConfigLoader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.json": {Data: []byte(`{"db":{"host":"db.local","port":5432,"tls":false},"tags":["a","b"],"name":"app"}`)},
+	}
+	defaults := parseObj(t, `{"db":{"host":"localhost","port":1,"timeout":1.5},"debug":false}`)
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("name", "", "")
+	flags.Int("db.port", 0, "")
+	flags.Bool("debug", false, "")
+	if err := flags.Parse([]string{"-db.port=7000"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewConfigLoader().
+		AddObj("defaults", defaults).
+		AddFileFS(fsys, "app.json", false).
+		AddFileFS(fsys, "missing.json", true).
+		AddEnvFrom("APP", []string{"APP_DB__HOST=db.prod", "APP_DB__TLS=1", "APP_DB__TIMEOUT=3", "APP_TAGS=x, y", "APP_NEW__KEY=42", "OTHER=1"}).
+		AddFlags(flags).
+		Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"db":{"host":"db.prod","port":7000,"timeout":3,"tls":true},"debug":false,"name":"app","new":{"key":42},"tags":["x","y"]}`
+	if !Equal(cfg, parseObj(t, expected)) {
+		t.Fatal("unexpected", cfg.Obj)
+	}
+	if _, ok := cfg.Get("db").(Object)["timeout"].(float64); !ok {
+		t.Fatal("unexpected", cfg.Get("db"))
+	}
+
+	sources := map[string]string{
+		"db.host":    "env:APP_DB__HOST",
+		"db.port":    "flag:db.port",
+		"db.timeout": "env:APP_DB__TIMEOUT",
+		"debug":      "defaults",
+		"name":       "app.json",
+		"tags[1]":    "env:APP_TAGS",
+		"unknown":    "",
+	}
+	for path, source := range sources {
+		if s := cfg.Source(path); s != source {
+			t.Fatal("unexpected", path, s)
+		}
+	}
+}
+
+func TestConfigLoader_Errors(t *testing.T) {
+	_, err := NewConfigLoader().AddFileFS(fstest.MapFS{}, "app.json", false).Load()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	_, err = NewConfigLoader().
+		AddObj("defaults", parseObj(t, `{"port":1}`)).
+		AddEnvFrom("APP_", []string{"APP_PORT=abc"}).
+		Load()
+	if err == nil || !strings.Contains(err.Error(), "env:APP_PORT") {
+		t.Fatal("unexpected", err)
+	}
+
+	_, err = NewConfigLoader().
+		AddObj("defaults", parseObj(t, `{"port":1}`)).
+		AddValidator(RequireKeys("port", "db.host", "db.user")).
+		AddValidator(func(cfg *Config) error {
+			return nil
+		}).
+		Load()
+	if err == nil || !strings.Contains(err.Error(), "db.host") || !strings.Contains(err.Error(), "db.user") {
+		t.Fatal("unexpected", err)
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		str      string
+		current  interface{}
+		expected string
+	}{
+		{"42", nil, "42"},
+		{"4.5", nil, "4.5"},
+		{"true", nil, "true"},
+		{"0x10", nil, `"0x10"`},
+		{"text", nil, `"text"`},
+		{"1", false, "true"},
+		{"7", 1.5, "7"},
+		{"1,2", &Array{}, "[1,2]"},
+		{`["a",1]`, &Array{}, `["a",1]`},
+		{`{"a":1}`, Object{}, `{"a":1}`},
+		{"text", "old", `"text"`},
+	}
+	for _, test := range tests {
+		v, err := Coerce(test.str, test.current)
+		if err != nil {
+			t.Fatal(test.str, err)
+		}
+		if s := diffValue(v); s != test.expected {
+			t.Fatal("unexpected", test.str, s)
+		}
+	}
+
+	if _, err := Coerce("abc", int64(1)); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// containers are parsed strictly as json of the same type
+	for _, str := range []string{"[1]", "null", "<a/>", `{"a":1} x`} {
+		if _, err := Coerce(str, Object{}); !errors.Is(err, ErrConversion) {
+			t.Fatal("unexpected", str, err)
+		}
+	}
+	if _, err := Coerce(`[1,]`, &Array{}); !errors.Is(err, ErrConversion) {
+		t.Fatal("unexpected", err)
+	}
+}
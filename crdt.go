@@ -0,0 +1,1105 @@
+package xobj
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file contains a JSON CRDT. Object members are last-writer-wins registers and arrays are replicated
+// growable arrays (RGA). Every modification is an operation, which is identified by a Lamport timestamp and
+// the replica, so that all replicas order concurrent operations equally and converge to the same document.
+
+// OpID identifies an operation of a CRDTDoc. The Lamport counter and the replica make it unique and
+// totally ordered, the zero value denotes the root object and the beginning of arrays.
+type OpID struct {
+	Counter uint64
+	Replica string
+}
+
+func (id OpID) less(o OpID) bool {
+	if id.Counter != o.Counter {
+		return id.Counter < o.Counter
+	}
+	return id.Replica < o.Replica
+}
+
+func (id OpID) isZero() bool {
+	return id.Counter == 0 && id.Replica == ""
+}
+
+// String returns the notation counter@replica or the empty string for the zero value
+func (id OpID) String() string {
+	if id.isZero() {
+		return ""
+	}
+	return strconv.FormatUint(id.Counter, 10) + "@" + id.Replica
+}
+
+func parseOpID(str string) (OpID, error) {
+	if str == "" {
+		return OpID{}, nil
+	}
+	counter, replica, ok := strings.Cut(str, "@")
+	if !ok {
+		return OpID{}, fmt.Errorf("invalid operation id '%s'", str)
+	}
+	n, err := strconv.ParseUint(counter, 10, 64)
+	if err != nil {
+		return OpID{}, fmt.Errorf("invalid operation id '%s': %w", str, err)
+	}
+	return OpID{Counter: n, Replica: replica}, nil
+}
+
+// A VersionVector contains the Lamport counter per replica, up to which all operations of the replica have
+// been applied. Operations which arrive out of order are pending and do not advance the vector, so that a
+// delta for the vector never skips them.
+type VersionVector map[string]uint64
+
+// the kinds of operations
+const (
+	opSet        = "s" // set an object member
+	opDelete     = "d" // delete an object member
+	opSetElem    = "S" // set an array element
+	opDeleteElem = "D" // delete an array element
+	opInsert     = "i" // insert an array element after a reference element
+)
+
+// crdtOp is serialized as [kind, id, prev, node, key or reference, value]. An empty object or array as value
+// creates a new nested container, whose node id is the id of the operation. Prev is the counter of the
+// previous operation of the same replica, so that the operations of each replica are applied in order.
+type crdtOp struct {
+	kind  string
+	id    OpID
+	prev  uint64
+	node  OpID
+	key   string
+	ref   OpID
+	value interface{}
+}
+
+func (op *crdtOp) hasValue() bool {
+	return op.kind == opSet || op.kind == opSetElem || op.kind == opInsert
+}
+
+func (op *crdtOp) MarshalJSON() ([]byte, error) {
+	target := op.key
+	if op.kind != opSet && op.kind != opDelete {
+		target = op.ref.String()
+	}
+	res := []interface{}{op.kind, op.id.String(), op.prev, op.node.String(), target}
+	if op.hasValue() {
+		res = append(res, op.value)
+	}
+	return json.Marshal(res)
+}
+
+func (op *crdtOp) UnmarshalJSON(data []byte) error {
+	// numbers are decoded like #crdtPrimitive() normalizes local values
+	var list []interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&list); err != nil {
+		return err
+	}
+	if len(list) < 5 {
+		return fmt.Errorf("invalid operation %s", data)
+	}
+	var str [4]string
+	for i, idx := range []int{0, 1, 3, 4} {
+		s, ok := list[idx].(string)
+		if !ok {
+			return fmt.Errorf("invalid operation %s", data)
+		}
+		str[i] = s
+	}
+	prev, ok := list[2].(json.Number)
+	if !ok {
+		return fmt.Errorf("invalid operation %s", data)
+	}
+
+	op.kind = str[0]
+	var err error
+	if op.id, err = parseOpID(str[1]); err != nil {
+		return err
+	}
+	if op.node, err = parseOpID(str[2]); err != nil {
+		return err
+	}
+	switch op.kind {
+	case opSet, opDelete:
+		op.key = str[3]
+	case opSetElem, opDeleteElem, opInsert:
+		if op.ref, err = parseOpID(str[3]); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown operation '%s'", op.kind)
+	}
+	if op.prev, err = strconv.ParseUint(prev.String(), 10, 64); err != nil || op.id.isZero() || op.prev >= op.id.Counter {
+		return fmt.Errorf("invalid operation %s", data)
+	}
+	if op.hasValue() {
+		if len(list) != 6 {
+			return fmt.Errorf("invalid operation %s", data)
+		}
+		op.value = list[5]
+		if !isContainer(op.value) {
+			op.value = crdtPrimitive(op.value)
+		}
+	}
+	return nil
+}
+
+// crdtNode is either an object with registers per key or an array of elements
+type crdtNode struct {
+	id     OpID
+	list   bool
+	fields map[string]*crdtRegister
+	elems  []*crdtElem
+}
+
+// crdtRegister is a last-writer-wins register, whose value may be a *crdtNode
+type crdtRegister struct {
+	id      OpID
+	value   interface{}
+	deleted bool
+}
+
+// crdtElem is an element of an RGA, which is never removed but marked as deleted
+type crdtElem struct {
+	id      OpID
+	reg     crdtRegister
+	deleted bool
+}
+
+// crdtState is the replicated state and the log of all applied operations
+type crdtState struct {
+	replica string
+	clock   uint64
+	version VersionVector
+	nodes   map[OpID]*crdtNode
+	elems   map[OpID]*crdtElem
+	seen    map[OpID]bool
+	log     []*crdtOp
+	pending []*crdtOp
+}
+
+func newCRDTState(replica string) *crdtState {
+	root := &crdtNode{fields: map[string]*crdtRegister{}}
+	return &crdtState{
+		replica: replica,
+		version: VersionVector{},
+		nodes:   map[OpID]*crdtNode{{}: root},
+		elems:   map[OpID]*crdtElem{},
+		seen:    map[OpID]bool{},
+	}
+}
+
+// apply returns false, if the operation depends on another one which has not been applied yet
+func (s *crdtState) apply(op *crdtOp) (bool, error) {
+	if s.seen[op.id] {
+		return true, nil
+	}
+	switch last := s.version[op.id.Replica]; {
+	case op.prev > last:
+		return false, nil
+	case op.prev < last:
+		return false, fmt.Errorf("operation %s conflicts with another operation of replica '%s'", op.id, op.id.Replica)
+	}
+	node := s.nodes[op.node]
+	if node == nil {
+		return false, nil
+	}
+	if node.list != (op.kind != opSet && op.kind != opDelete) {
+		return false, fmt.Errorf("operation %s does not match the container %s", op.id, op.node)
+	}
+
+	switch op.kind {
+	case opSet, opDelete:
+		reg := node.fields[op.key]
+		if reg == nil {
+			reg = &crdtRegister{}
+			node.fields[op.key] = reg
+		}
+		s.assign(reg, op)
+	case opSetElem, opDeleteElem:
+		e := s.elems[op.ref]
+		if e == nil {
+			return false, nil
+		}
+		if op.kind == opDeleteElem {
+			e.deleted = true
+		} else {
+			s.assign(&e.reg, op)
+		}
+	case opInsert:
+		pos := 0
+		if !op.ref.isZero() {
+			ref := s.elems[op.ref]
+			if ref == nil {
+				return false, nil
+			}
+			for i, e := range node.elems {
+				if e == ref {
+					pos = i + 1
+					break
+				}
+			}
+		}
+		// concurrent inserts at the same position are ordered by descending id
+		for pos < len(node.elems) && op.id.less(node.elems[pos].id) {
+			pos++
+		}
+		e := &crdtElem{id: op.id}
+		s.assign(&e.reg, op)
+		node.elems = append(node.elems, nil)
+		copy(node.elems[pos+1:], node.elems[pos:])
+		node.elems[pos] = e
+		s.elems[op.id] = e
+	}
+
+	s.seen[op.id] = true
+	s.log = append(s.log, op)
+	if op.id.Counter > s.clock {
+		s.clock = op.id.Counter
+	}
+	s.version[op.id.Replica] = op.id.Counter
+	return true, nil
+}
+
+// assign updates the register, if the operation is newer. Containers are always created, so that the
+// operations on their content can be applied, even if they are not visible.
+func (s *crdtState) assign(reg *crdtRegister, op *crdtOp) {
+	value := op.value
+	if _, ok := toObj(value); ok {
+		n := &crdtNode{id: op.id, fields: map[string]*crdtRegister{}}
+		s.nodes[op.id] = n
+		value = n
+	} else if _, ok := toArr(value); ok && !isNil(value) {
+		n := &crdtNode{id: op.id, list: true}
+		s.nodes[op.id] = n
+		value = n
+	}
+
+	if reg.id.less(op.id) {
+		reg.id = op.id
+		reg.value = value
+		reg.deleted = op.kind == opDelete
+	}
+}
+
+// local creates, applies and logs a new operation of this replica
+func (s *crdtState) local(kind string, node OpID, key string, ref OpID, value interface{}) OpID {
+	s.clock++
+	op := &crdtOp{kind: kind, id: OpID{Counter: s.clock, Replica: s.replica}, prev: s.version[s.replica], node: node, key: key, ref: ref, value: value}
+	if _, err := s.apply(op); err != nil {
+		panic(err) // local operations are always valid
+	}
+	return op.id
+}
+
+// put creates the operations for the value, including those for the content of containers
+func (s *crdtState) put(kind string, node OpID, key string, ref OpID, value interface{}) OpID {
+	if obj, ok := toObj(value); ok && !isNil(value) {
+		id := s.local(kind, node, key, ref, map[string]interface{}{})
+		for _, k := range sortedKeys(obj) {
+			s.put(opSet, id, k, OpID{}, obj.Get(k))
+		}
+		return id
+	}
+	if arr, ok := toArr(value); ok && !isNil(value) {
+		id := s.local(kind, node, key, ref, []interface{}{})
+		prev := OpID{}
+		for i := 0; i < arr.Size(); i++ {
+			prev = s.put(opInsert, id, "", prev, arr.Get(i))
+		}
+		return id
+	}
+	return s.local(kind, node, key, ref, crdtPrimitive(value))
+}
+
+// crdtPrimitive normalizes values, so that all replicas see the same value after the serialization. Numbers
+// without a fraction become int64 and others float64, bytes become base64 like the json package writes them.
+func crdtPrimitive(v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil, string, bool:
+		return v
+	case []byte:
+		return base64.StdEncoding.EncodeToString(t)
+	case time.Time:
+		return formatTime(t)
+	case time.Duration:
+		return formatDuration(t)
+	}
+	if n, ok := toNumber(v); ok {
+		if n.isInt {
+			return n.i
+		}
+		return n.f
+	}
+	if isNil(v) {
+		return nil
+	}
+	return ToString(v)
+}
+
+// applyAll applies the operations and the pending ones until nothing changes anymore. An invalid operation
+// is dropped and returned as error, the remaining ones are kept as pending for the next delta.
+func (s *crdtState) applyAll(ops []*crdtOp) error {
+	queue := append(s.pending, ops...)
+	s.pending = nil
+	for {
+		var rest []*crdtOp
+		for i, op := range queue {
+			ok, err := s.apply(op)
+			if err != nil {
+				s.pending = append(rest, queue[i+1:]...)
+				return err
+			}
+			if !ok {
+				rest = append(rest, op)
+			}
+		}
+		if len(rest) == 0 || len(rest) == len(queue) {
+			s.pending = rest
+			return nil
+		}
+		queue = rest
+	}
+}
+
+func (s *crdtState) value(reg *crdtRegister) interface{} {
+	switch t := reg.value.(type) {
+	case *crdtNode:
+		if t.list {
+			return &CRDTArr{state: s, node: t}
+		}
+		return &CRDTObj{state: s, node: t}
+	}
+	return reg.value
+}
+
+// materialize converts a node into an Object or *Array
+func materialize(n *crdtNode) interface{} {
+	if n.list {
+		res := Array{}
+		for _, e := range n.elems {
+			if !e.deleted {
+				res = append(res, materializeValue(e.reg.value))
+			}
+		}
+		return &res
+	}
+	res := Object{}
+	for k, reg := range n.fields {
+		if !reg.deleted && !reg.id.isZero() {
+			res[k] = materializeValue(reg.value)
+		}
+	}
+	return res
+}
+
+func materializeValue(v interface{}) interface{} {
+	if n, ok := v.(*crdtNode); ok {
+		return materialize(n)
+	}
+	return v
+}
+
+//=
+
+// A CRDTDoc is a document, which can be modified concurrently by multiple replicas and merged without
+// conflicts. Object members are last-writer-wins registers, so concurrent puts of the same key keep the
+// value of the operation with the higher Lamport timestamp, even if both values are objects. Arrays are
+// sequence CRDTs, which keep concurrent insertions of all replicas in a deterministic order. The root and
+// all nested containers implement Obj and Arr, each modification creates operations which can be
+// exchanged by #CRDTDoc.Delta() and #CRDTDoc.ApplyDelta(). It is not thread safe.
+type CRDTDoc struct {
+	*CRDTObj
+}
+
+// NewCRDTDoc creates an empty document for the given replica, whose identifier must be unique among all
+// replicas, e.g. a random uuid per installation.
+func NewCRDTDoc(replica string) *CRDTDoc {
+	s := newCRDTState(replica)
+	return &CRDTDoc{CRDTObj: &CRDTObj{state: s, node: s.nodes[OpID{}]}}
+}
+
+// LoadCRDTDoc restores a document from the state returned by #CRDTDoc.MarshalState(). The replica may
+// differ from the one which has saved the state, e.g. to fork a document.
+func LoadCRDTDoc(replica string, state []byte) (*CRDTDoc, error) {
+	var tmp struct {
+		Ops     []*crdtOp `json:"ops"`
+		Pending []*crdtOp `json:"pending"`
+	}
+	if err := json.Unmarshal(state, &tmp); err != nil {
+		return nil, err
+	}
+	d := NewCRDTDoc(replica)
+	if err := d.state.applyAll(append(tmp.Ops, tmp.Pending...)); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Replica returns the identifier of this replica
+func (d *CRDTDoc) Replica() string {
+	return d.state.replica
+}
+
+// Version returns a copy of the version vector, which is sent to other replicas to request a delta.
+func (d *CRDTDoc) Version() VersionVector {
+	res := VersionVector{}
+	for k, v := range d.state.version {
+		res[k] = v
+	}
+	return res
+}
+
+// Delta returns all operations as a compact json array, which are newer than the given version vector. A nil
+// vector returns all operations.
+func (d *CRDTDoc) Delta(since VersionVector) ([]byte, error) {
+	ops := make([]*crdtOp, 0, len(d.state.log))
+	for _, op := range d.state.log {
+		if op.id.Counter > since[op.id.Replica] {
+			ops = append(ops, op)
+		}
+	}
+	return json.Marshal(ops)
+}
+
+// ApplyDelta applies the operations of another replica. Operations which are already known are ignored,
+// so deltas can be applied multiple times and in any order. Operations whose dependencies are missing are
+// kept until the dependencies arrive, see #CRDTDoc.Pending().
+func (d *CRDTDoc) ApplyDelta(delta []byte) error {
+	var ops []*crdtOp
+	if err := json.Unmarshal(delta, &ops); err != nil {
+		return err
+	}
+	return d.state.applyAll(ops)
+}
+
+// Merge applies all operations of the other document, which are unknown to this one.
+func (d *CRDTDoc) Merge(other *CRDTDoc) error {
+	return d.state.applyAll(other.state.log)
+}
+
+// Pending returns the amount of received operations, which cannot be applied yet.
+func (d *CRDTDoc) Pending() int {
+	return len(d.state.pending)
+}
+
+// MarshalState serializes the entire state including the history of operations, which is required to merge
+// with other replicas later on.
+func (d *CRDTDoc) MarshalState() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"ops":     d.state.log,
+		"pending": d.state.pending,
+	})
+}
+
+//==
+
+var _ Obj = (*CRDTObj)(nil)
+
+// A CRDTObj is an object within a CRDTDoc.
+type CRDTObj struct {
+	state *crdtState
+	node  *crdtNode
+}
+
+func (o *CRDTObj) register(name string) (*crdtRegister, bool) {
+	reg := o.node.fields[name]
+	if reg == nil || reg.deleted || reg.id.isZero() {
+		return nil, false
+	}
+	return reg, true
+}
+
+func (o *CRDTObj) raw(name string) (interface{}, bool) {
+	reg, ok := o.register(name)
+	if !ok {
+		return nil, false
+	}
+	return reg.value, true
+}
+
+func (o *CRDTObj) Keys() StrList {
+	res := make(StringList, 0, len(o.node.fields))
+	for k := range o.node.fields {
+		if _, ok := o.register(k); ok {
+			res = append(res, k)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (o *CRDTObj) Get(name string) interface{} {
+	reg, ok := o.register(name)
+	if !ok {
+		return nil
+	}
+	return o.state.value(reg)
+}
+
+func (o *CRDTObj) Put(name string, value interface{}) Obj {
+	o.state.put(opSet, o.node.id, name, OpID{}, value)
+	return o
+}
+
+func (o *CRDTObj) Remove(name string) Obj {
+	if o.Has(name) {
+		o.state.local(opDelete, o.node.id, name, OpID{}, nil)
+	}
+	return o
+}
+
+func (o *CRDTObj) Has(name string) bool {
+	_, ok := o.register(name)
+	return ok
+}
+
+func (o *CRDTObj) IsNull(name string) bool {
+	return o.Get(name) == nil
+}
+
+func (o *CRDTObj) AsInt64(name string) (int64, error) {
+	v, ok := o.raw(name)
+	if !ok {
+		return 0, unknownFieldName(name)
+	}
+	res, err := asInt64(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *CRDTObj) OptInt64(name string, fallback int64) int64 {
+	v, err := o.AsInt64(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *CRDTObj) PutInt64(name string, value int64) Obj {
+	return o.Put(name, value)
+}
+
+func (o *CRDTObj) AsBool(name string) (bool, error) {
+	v, ok := o.raw(name)
+	if !ok {
+		return false, unknownFieldName(name)
+	}
+	res, err := asBool(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *CRDTObj) OptBool(name string, fallback bool) bool {
+	v, err := o.AsBool(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *CRDTObj) PutBool(name string, value bool) Obj {
+	return o.Put(name, value)
+}
+
+func (o *CRDTObj) AsFloat64(name string) (float64, error) {
+	v, ok := o.raw(name)
+	if !ok {
+		return 0, unknownFieldName(name)
+	}
+	res, err := asFloat64(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *CRDTObj) OptFloat64(name string, fallback float64) float64 {
+	v, err := o.AsFloat64(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *CRDTObj) PutFloat64(name string, value float64) Obj {
+	return o.Put(name, value)
+}
+
+func (o *CRDTObj) AsString(name string) (string, error) {
+	v, ok := o.raw(name)
+	if !ok {
+		return "", unknownFieldName(name)
+	}
+	if n, ok := v.(*crdtNode); ok {
+		return "", withPath(Path{name}, typeMismatch(materialize(n), "string"))
+	}
+	res, err := asString(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *CRDTObj) OptString(name string, fallback string) string {
+	v, err := o.AsString(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *CRDTObj) PutString(name string, value string) Obj {
+	return o.Put(name, value)
+}
+
+func (o *CRDTObj) AsTime(name string) (time.Time, error) {
+	v, ok := o.raw(name)
+	if !ok {
+		return time.Time{}, unknownFieldName(name)
+	}
+	res, err := asTime(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *CRDTObj) OptTime(name string, fallback time.Time) time.Time {
+	v, err := o.AsTime(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *CRDTObj) PutTime(name string, value time.Time) Obj {
+	return o.Put(name, value)
+}
+
+func (o *CRDTObj) AsDuration(name string) (time.Duration, error) {
+	v, ok := o.raw(name)
+	if !ok {
+		return 0, unknownFieldName(name)
+	}
+	res, err := asDuration(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *CRDTObj) OptDuration(name string, fallback time.Duration) time.Duration {
+	v, err := o.AsDuration(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *CRDTObj) PutDuration(name string, value time.Duration) Obj {
+	return o.Put(name, value)
+}
+
+func (o *CRDTObj) AsBytes(name string) ([]byte, error) {
+	v, ok := o.raw(name)
+	if !ok {
+		return nil, unknownFieldName(name)
+	}
+	res, err := asBytes(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *CRDTObj) OptBytes(name string, fallback []byte) []byte {
+	v, err := o.AsBytes(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *CRDTObj) PutBytes(name string, value []byte) Obj {
+	return o.Put(name, value)
+}
+
+func (o *CRDTObj) AsObject(name string) (Obj, error) {
+	v, ok := o.raw(name)
+	if !ok {
+		return nil, unknownFieldName(name)
+	}
+	if n, ok := v.(*crdtNode); ok && !n.list {
+		return &CRDTObj{state: o.state, node: n}, nil
+	}
+	return nil, withPath(Path{name}, typeMismatch(v, "object"))
+}
+
+func (o *CRDTObj) OptObject(name string) Obj {
+	v, err := o.AsObject(name)
+	if err != nil {
+		o.Put(name, Object{})
+		v, _ = o.AsObject(name)
+	}
+	return v
+}
+
+func (o *CRDTObj) PutObject(name string, value Obj) Obj {
+	return o.Put(name, value)
+}
+
+func (o *CRDTObj) AsArray(name string) (Arr, error) {
+	v, ok := o.raw(name)
+	if !ok {
+		return nil, unknownFieldName(name)
+	}
+	if n, ok := v.(*crdtNode); ok && n.list {
+		return &CRDTArr{state: o.state, node: n}, nil
+	}
+	return nil, withPath(Path{name}, typeMismatch(v, "array"))
+}
+
+func (o *CRDTObj) OptArray(name string) Arr {
+	v, err := o.AsArray(name)
+	if err != nil {
+		o.Put(name, &Array{})
+		v, _ = o.AsArray(name)
+	}
+	return v
+}
+
+func (o *CRDTObj) PutArray(name string, value Arr) Obj {
+	return o.Put(name, value)
+}
+
+// MarshalJSON serializes the current content
+func (o *CRDTObj) MarshalJSON() ([]byte, error) {
+	return json.Marshal(materialize(o.node))
+}
+
+func (o *CRDTObj) String() string {
+	return materialize(o.node).(Object).String()
+}
+
+//==
+
+var _ Arr = (*CRDTArr)(nil)
+
+// A CRDTArr is an array within a CRDTDoc.
+type CRDTArr struct {
+	state *crdtState
+	node  *crdtNode
+}
+
+// elem returns the visible element at the index
+func (a *CRDTArr) elem(idx int) (*crdtElem, bool) {
+	if idx < 0 {
+		return nil, false
+	}
+	for _, e := range a.node.elems {
+		if e.deleted {
+			continue
+		}
+		if idx == 0 {
+			return e, true
+		}
+		idx--
+	}
+	return nil, false
+}
+
+func (a *CRDTArr) element(idx int) (interface{}, error) {
+	e, ok := a.elem(idx)
+	if !ok {
+		return nil, outOfBoundsAt(idx, a.Size())
+	}
+	return e.reg.value, nil
+}
+
+func (a *CRDTArr) mustElem(idx int) *crdtElem {
+	e, ok := a.elem(idx)
+	if !ok {
+		panic(outOfBoundsAt(idx, a.Size()))
+	}
+	return e
+}
+
+// add inserts after the last element, including deleted ones
+func (a *CRDTArr) add(value interface{}) Arr {
+	ref := OpID{}
+	if n := len(a.node.elems); n > 0 {
+		ref = a.node.elems[n-1].id
+	}
+	a.state.put(opInsert, a.node.id, "", ref, value)
+	return a
+}
+
+func (a *CRDTArr) Size() int {
+	n := 0
+	for _, e := range a.node.elems {
+		if !e.deleted {
+			n++
+		}
+	}
+	return n
+}
+
+func (a *CRDTArr) Get(idx int) interface{} {
+	return a.state.value(&a.mustElem(idx).reg)
+}
+
+func (a *CRDTArr) Put(idx int, value interface{}) Arr {
+	e := a.mustElem(idx)
+	a.state.put(opSetElem, a.node.id, "", e.id, value)
+	return a
+}
+
+func (a *CRDTArr) IsNull(idx int) bool {
+	e, ok := a.elem(idx)
+	return !ok || e.reg.value == nil
+}
+
+func (a *CRDTArr) Remove(idx int) Arr {
+	e := a.mustElem(idx)
+	a.state.local(opDeleteElem, a.node.id, "", e.id, nil)
+	return a
+}
+
+func (a *CRDTArr) AsInt64(idx int) (int64, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := asInt64(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *CRDTArr) OptInt64(idx int, fallback int64) int64 {
+	v, err := a.AsInt64(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *CRDTArr) PutInt64(idx int, value int64) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *CRDTArr) AddInt64(value int64) Arr {
+	return a.add(value)
+}
+
+func (a *CRDTArr) AsBool(idx int) (bool, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return false, err
+	}
+	res, err := asBool(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *CRDTArr) OptBool(idx int, fallback bool) bool {
+	v, err := a.AsBool(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *CRDTArr) PutBool(idx int, value bool) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *CRDTArr) AddBool(value bool) Arr {
+	return a.add(value)
+}
+
+func (a *CRDTArr) AsFloat64(idx int) (float64, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := asFloat64(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *CRDTArr) OptFloat64(idx int, fallback float64) float64 {
+	v, err := a.AsFloat64(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *CRDTArr) PutFloat64(idx int, value float64) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *CRDTArr) AddFloat64(value float64) Arr {
+	return a.add(value)
+}
+
+func (a *CRDTArr) AsString(idx int) (string, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return "", err
+	}
+	if n, ok := v.(*crdtNode); ok {
+		return "", withPath(Path{idx}, typeMismatch(materialize(n), "string"))
+	}
+	res, err := asString(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *CRDTArr) OptString(idx int, fallback string) string {
+	v, err := a.AsString(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *CRDTArr) PutString(idx int, value string) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *CRDTArr) AddString(value string) Arr {
+	return a.add(value)
+}
+
+func (a *CRDTArr) AsTime(idx int) (time.Time, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	res, err := asTime(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *CRDTArr) OptTime(idx int, fallback time.Time) time.Time {
+	v, err := a.AsTime(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *CRDTArr) PutTime(idx int, value time.Time) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *CRDTArr) AddTime(value time.Time) Arr {
+	return a.add(value)
+}
+
+func (a *CRDTArr) AsDuration(idx int) (time.Duration, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := asDuration(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *CRDTArr) OptDuration(idx int, fallback time.Duration) time.Duration {
+	v, err := a.AsDuration(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *CRDTArr) PutDuration(idx int, value time.Duration) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *CRDTArr) AddDuration(value time.Duration) Arr {
+	return a.add(value)
+}
+
+func (a *CRDTArr) AsBytes(idx int) ([]byte, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := asBytes(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *CRDTArr) OptBytes(idx int, fallback []byte) []byte {
+	v, err := a.AsBytes(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *CRDTArr) PutBytes(idx int, value []byte) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *CRDTArr) AddBytes(value []byte) Arr {
+	return a.add(value)
+}
+
+func (a *CRDTArr) AsObject(idx int) (Obj, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return nil, err
+	}
+	if n, ok := v.(*crdtNode); ok && !n.list {
+		return &CRDTObj{state: a.state, node: n}, nil
+	}
+	return nil, withPath(Path{idx}, typeMismatch(v, "object"))
+}
+
+func (a *CRDTArr) OptObject(idx int) Obj {
+	v, err := a.AsObject(idx)
+	if err != nil {
+		a.Put(idx, Object{})
+		v, _ = a.AsObject(idx)
+	}
+	return v
+}
+
+func (a *CRDTArr) PutObject(idx int, value Obj) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *CRDTArr) AddObject(value Obj) Arr {
+	return a.add(value)
+}
+
+func (a *CRDTArr) AsArray(idx int) (Arr, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return nil, err
+	}
+	if n, ok := v.(*crdtNode); ok && n.list {
+		return &CRDTArr{state: a.state, node: n}, nil
+	}
+	return nil, withPath(Path{idx}, typeMismatch(v, "array"))
+}
+
+func (a *CRDTArr) OptArray(idx int) Arr {
+	v, err := a.AsArray(idx)
+	if err != nil {
+		a.Put(idx, &Array{})
+		v, _ = a.AsArray(idx)
+	}
+	return v
+}
+
+func (a *CRDTArr) PutArray(idx int, value Arr) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *CRDTArr) AddArray(value Arr) Arr {
+	return a.add(value)
+}
+
+// MarshalJSON serializes the current content
+func (a *CRDTArr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(materialize(a.node))
+}
+
+func (a *CRDTArr) String() string {
+	return materialize(a.node).(*Array).String()
+}
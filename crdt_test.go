@@ -0,0 +1,139 @@
+package xobj
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCRDTDoc(t *testing.T) {
+	a := NewCRDTDoc("a")
+	a.PutString("title", "draft").OptArray("list").AddString("x").AddString("y")
+	a.OptObject("meta").PutInt64("rev", 1)
+
+	b, err := LoadCRDTDoc("b", mustState(t, a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != a.String() {
+		t.Fatal("unexpected", b.String())
+	}
+
+	// concurrent edits
+	a.PutString("title", "from a")
+	a.OptArray("list").Remove(0)
+	a.OptArray("list").AddString("a1")
+	b.PutString("title", "from b")
+	b.OptArray("list").AddString("b1")
+	b.OptObject("meta").PutBool("ok", true)
+
+	da, err := a.Delta(b.Version())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := b.Delta(a.Version())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.ApplyDelta(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.ApplyDelta(da); err != nil {
+		t.Fatal(err)
+	}
+	// applying again has no effect
+	if err := b.ApplyDelta(da); err != nil {
+		t.Fatal(err)
+	}
+
+	if a.String() != b.String() {
+		t.Fatal("not converged", a.String(), b.String())
+	}
+	// equal counters are ordered by the replica, concurrent inserts by descending counters
+	expected := `{"list":["y","a1","b1"],"meta":{"ok":true,"rev":1},"title":"from b"}`
+	if a.String() != expected {
+		t.Fatal("unexpected", a.String())
+	}
+}
+
+func TestCRDTDoc_OutOfOrder(t *testing.T) {
+	a := NewCRDTDoc("a")
+	a.OptObject("o").PutString("k", "v")
+	first, _ := a.Delta(nil)
+	v := a.Version()
+	a.OptObject("o").OptArray("l").AddInt64(1)
+	second, _ := a.Delta(v)
+
+	b := NewCRDTDoc("b")
+	if err := b.ApplyDelta(second); err != nil {
+		t.Fatal(err)
+	}
+	if b.Pending() == 0 || b.Has("o") || b.Version()["a"] != 0 {
+		t.Fatal("unexpected", b.Pending(), b.String(), b.Version())
+	}
+
+	// the pending operations are not skipped by a delta for the current version
+	missing, _ := a.Delta(b.Version())
+	if err := b.ApplyDelta(missing); err != nil {
+		t.Fatal(err)
+	}
+	if b.Pending() != 0 || b.String() != `{"o":{"k":"v","l":[1]}}` {
+		t.Fatal("unexpected", b.Pending(), b.String())
+	}
+	b = NewCRDTDoc("b")
+	if err := b.ApplyDelta(second); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.ApplyDelta(first); err != nil {
+		t.Fatal(err)
+	}
+	if b.Pending() != 0 || b.String() != `{"o":{"k":"v","l":[1]}}` {
+		t.Fatal("unexpected", b.Pending(), b.String())
+	}
+}
+
+func TestCRDTDoc_InvalidDelta(t *testing.T) {
+	b := NewCRDTDoc("b")
+	bad := `[["s","1@x",0,"","l",[]],["s","2@x",1,"1@x","k","v"],["s","1@y",0,"","z",true]]`
+	if err := b.ApplyDelta([]byte(bad)); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := b.ApplyDelta([]byte(`[["s","1@w",0,"","w",1]]`)); err != nil {
+		t.Fatal(err)
+	}
+	if b.Pending() != 0 || b.String() != `{"l":[],"w":1,"z":true}` {
+		t.Fatal("unexpected", b.Pending(), b.String())
+	}
+	if _, err := b.AsString("l"); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatal("unexpected", err)
+	}
+}
+
+func TestCRDTDoc_ValueTypes(t *testing.T) {
+	a := NewCRDTDoc("a")
+	a.PutInt64("i", 1<<60).PutFloat64("f", 1.5).PutFloat64("whole", 2).PutBytes("b", []byte{0xd7, 0x6d, 0xf8})
+	delta, _ := a.Delta(nil)
+	b := NewCRDTDoc("b")
+	if err := b.ApplyDelta(delta); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"i", "f", "whole", "b"} {
+		if va, vb := a.Get(k), b.Get(k); va != vb {
+			t.Fatalf("unexpected %s: %#v != %#v", k, va, vb)
+		}
+	}
+	if v, err := b.AsBytes("b"); err != nil || string(v) != string([]byte{0xd7, 0x6d, 0xf8}) {
+		t.Fatal("unexpected", v, err)
+	}
+	if v, err := b.AsInt64("i"); err != nil || v != 1<<60 {
+		t.Fatal("unexpected", v, err)
+	}
+}
+
+func mustState(t *testing.T, d *CRDTDoc) []byte {
+	t.Helper()
+	state, err := d.MarshalState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
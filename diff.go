@@ -0,0 +1,307 @@
+package xobj
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DiffKind is the kind of a Difference.
+type DiffKind int
+
+const (
+	// DiffAdded is a value which only exists in the new version
+	DiffAdded DiffKind = iota + 1
+	// DiffRemoved is a value which only exists in the old version
+	DiffRemoved
+	// DiffChanged is a value which exists in both versions but differs
+	DiffChanged
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(k))
+}
+
+// A Difference is a single structural difference between two documents. The path refers to the old version
+// for removed and changed values and to the new version for added values.
+type Difference struct {
+	Path Path
+	Kind DiffKind
+	Old  interface{}
+	New  interface{}
+}
+
+// PathString returns the path in the notation of #ParsePath(), which is easier to use with gomobile.
+func (d *Difference) PathString() string {
+	return d.Path.String()
+}
+
+func (d *Difference) String() string {
+	switch d.Kind {
+	case DiffAdded:
+		return "+ " + d.Path.String() + ": " + diffValue(d.New)
+	case DiffRemoved:
+		return "- " + d.Path.String() + ": " + diffValue(d.Old)
+	}
+	return "~ " + d.Path.String() + ": " + diffValue(d.Old) + " -> " + diffValue(d.New)
+}
+
+// diffValue formats values as json, so that strings and numbers can be distinguished
+func diffValue(v interface{}) string {
+	sb := &strings.Builder{}
+	if _, err := NewEncoder(sb, DefaultEncodeOptions()).encode(v); err != nil {
+		return ToString(v)
+	}
+	return sb.String()
+}
+
+// Diff returns the structural differences between the old and the new object, in document order with sorted
+// keys. Values are compared like #Equal() does. Array elements are matched by their longest common
+// subsequence, so that an insertion is reported as a single addition.
+func Diff(old, new Obj) []*Difference {
+	d := &differ{}
+	d.value(Path{}, toObjOrEmpty(old), toObjOrEmpty(new))
+	return d.res
+}
+
+type differ struct {
+	res []*Difference
+}
+
+func (d *differ) add(path Path, kind DiffKind, old, new interface{}) {
+	d.res = append(d.res, &Difference{Path: path, Kind: kind, Old: old, New: new})
+}
+
+func (d *differ) value(path Path, old, new interface{}) {
+	if EqualValues(old, new, EqualOptions{}) {
+		return
+	}
+
+	if oo, ok := toObj(old); ok && !isNil(old) {
+		if no, ok := toObj(new); ok && !isNil(new) {
+			d.object(path, oo, no)
+			return
+		}
+	}
+
+	if oa, ok := toArr(old); ok && !isNil(old) {
+		if na, ok := toArr(new); ok && !isNil(new) {
+			d.array(path, elements(oa), elements(na))
+			return
+		}
+	}
+
+	d.add(path, DiffChanged, old, new)
+}
+
+func (d *differ) object(path Path, old, new Obj) {
+	keys := sortedKeys(old)
+	for _, k := range sortedKeys(new) {
+		if !old.Has(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch {
+		case !new.Has(k):
+			d.add(path.Child(k), DiffRemoved, old.Get(k), nil)
+		case !old.Has(k):
+			d.add(path.Child(k), DiffAdded, nil, new.Get(k))
+		default:
+			d.value(path.Child(k), old.Get(k), new.Get(k))
+		}
+	}
+}
+
+// array pairs the unmatched elements between the common ones as changes, the rest is added or removed
+func (d *differ) array(path Path, old, new []interface{}) {
+	match := (&merger{}).lcs(old, new)
+	i, j := 0, 0
+	for {
+		next := i
+		for next < len(old) && match[next] < 0 {
+			next++
+		}
+		jn := len(new)
+		if next < len(old) {
+			jn = match[next]
+		}
+
+		for ; i < next && j < jn; i, j = i+1, j+1 {
+			d.value(path.Child(i), old[i], new[j])
+		}
+		for ; i < next; i++ {
+			d.add(path.Child(i), DiffRemoved, old[i], nil)
+		}
+		for ; j < jn; j++ {
+			d.add(path.Child(j), DiffAdded, nil, new[j])
+		}
+
+		if next == len(old) {
+			return
+		}
+		i, j = next+1, jn+1
+	}
+}
+
+// DiffOptions configure a #DiffReport().
+type DiffOptions struct {
+	// Unified shows a line based diff of the indented json, instead of one line per difference
+	Unified bool
+
+	// Context is the amount of unchanged lines around the changes of a unified diff
+	Context int
+
+	// Color uses ansi escape sequences for terminals
+	Color bool
+
+	// OldName and NewName are used in the header of the report, the defaults are "expected" and "actual"
+	OldName string
+	NewName string
+}
+
+const (
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+	ansiReset  = "\x1b[0m"
+)
+
+// DiffReport returns a human readable report of the differences or the empty string, if the objects are
+// equal. The default format lists each Difference on its own line, annotated with its path.
+func DiffReport(old, new Obj, opts DiffOptions) string {
+	diffs := Diff(old, new)
+	if len(diffs) == 0 {
+		return ""
+	}
+	if opts.OldName == "" {
+		opts.OldName = "expected"
+	}
+	if opts.NewName == "" {
+		opts.NewName = "actual"
+	}
+
+	sb := &strings.Builder{}
+	colored := func(color, line string) {
+		if opts.Color && color != "" {
+			sb.WriteString(color + line + ansiReset + "\n")
+			return
+		}
+		sb.WriteString(line + "\n")
+	}
+	colored(ansiRed, "--- "+opts.OldName)
+	colored(ansiGreen, "+++ "+opts.NewName)
+
+	if opts.Unified {
+		unifiedDiff(colored, jsonLines(old), jsonLines(new), opts.Context)
+		return sb.String()
+	}
+
+	for _, diff := range diffs {
+		switch diff.Kind {
+		case DiffAdded:
+			colored(ansiGreen, diff.String())
+		case DiffRemoved:
+			colored(ansiRed, diff.String())
+		default:
+			colored(ansiYellow, diff.String())
+		}
+	}
+	return sb.String()
+}
+
+func jsonLines(obj Obj) []interface{} {
+	sb := &strings.Builder{}
+	opts := DefaultEncodeOptions()
+	opts.Indent = "  "
+	if _, err := WriteTo(sb, toObjOrEmpty(obj), opts); err != nil {
+		return []interface{}{err.Error()}
+	}
+	lines := strings.Split(sb.String(), "\n")
+	res := make([]interface{}, len(lines))
+	for i, l := range lines {
+		res[i] = l
+	}
+	return res
+}
+
+// unifiedDiff writes the hunks of a line based diff
+func unifiedDiff(write func(color, line string), old, new []interface{}, context int) {
+	type line struct {
+		op   byte
+		text string
+		a, b int // line numbers in old and new
+	}
+	match := (&merger{}).lcs(old, new)
+	var lines []line
+	j := 0
+	for i := range old {
+		if match[i] < 0 {
+			lines = append(lines, line{op: '-', text: old[i].(string), a: i, b: j})
+			continue
+		}
+		for ; j < match[i]; j++ {
+			lines = append(lines, line{op: '+', text: new[j].(string), a: i, b: j})
+		}
+		lines = append(lines, line{op: ' ', text: old[i].(string), a: i, b: j})
+		j++
+	}
+	for ; j < len(new); j++ {
+		lines = append(lines, line{op: '+', text: new[j].(string), a: len(old), b: j})
+	}
+
+	// a line is shown, if a change is within the context distance
+	show := make([]bool, len(lines))
+	for i, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for k := i - context; k <= i+context; k++ {
+			if k >= 0 && k < len(lines) {
+				show[k] = true
+			}
+		}
+	}
+
+	for start := 0; start < len(lines); {
+		if !show[start] {
+			start++
+			continue
+		}
+		end := start
+		for end < len(lines) && show[end] {
+			end++
+		}
+		oldCount, newCount := 0, 0
+		for _, l := range lines[start:end] {
+			if l.op != '+' {
+				oldCount++
+			}
+			if l.op != '-' {
+				newCount++
+			}
+		}
+		write(ansiCyan, fmt.Sprintf("@@ -%d,%d +%d,%d @@", lines[start].a+1, oldCount, lines[start].b+1, newCount))
+		for _, l := range lines[start:end] {
+			switch l.op {
+			case '-':
+				write(ansiRed, "-"+l.text)
+			case '+':
+				write(ansiGreen, "+"+l.text)
+			default:
+				write("", " "+l.text)
+			}
+		}
+		start = end
+	}
+}
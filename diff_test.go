@@ -0,0 +1,44 @@
+package xobj
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := parseObj(t, `{"title":"a","n":1,"tags":["x","y","z"],"meta":{"a":1},"gone":null}`)
+	new := parseObj(t, `{"title":"b","n":1.0,"tags":["w","x","z"],"meta":{"a":1,"b":[]}}`)
+
+	var lines []string
+	for _, d := range Diff(old, new) {
+		lines = append(lines, d.String())
+	}
+	expected := []string{
+		`- gone: null`,
+		`+ meta.b: []`,
+		`+ tags[0]: "w"`,
+		`- tags[1]: "y"`,
+		`~ title: "a" -> "b"`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Fatal("unexpected", lines)
+	}
+
+	if DiffReport(old, old, DiffOptions{}) != "" {
+		t.Fatal("expected no report")
+	}
+}
+
+func TestDiffReport_Unified(t *testing.T) {
+	old := parseObj(t, `{"a":1,"b":2,"c":3,"d":4,"e":5,"f":6}`)
+	new := parseObj(t, `{"a":1,"b":2,"c":30,"d":4,"e":5,"f":6}`)
+
+	expected := "--- expected\n+++ actual\n@@ -3,3 +3,3 @@\n   \"b\": 2,\n-  \"c\": 3,\n+  \"c\": 30,\n   \"d\": 4,\n"
+	if s := DiffReport(old, new, DiffOptions{Unified: true, Context: 1}); s != expected {
+		t.Fatal("unexpected", s)
+	}
+
+	if s := DiffReport(old, new, DiffOptions{Color: true}); !strings.Contains(s, ansiYellow+"~ c: 3 -> 30"+ansiReset) {
+		t.Fatal("unexpected", s)
+	}
+}
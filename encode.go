@@ -0,0 +1,388 @@
+package xobj
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// EncodeOptions configure the json serialization of an Encoder. The zero value writes compact json
+// without html escaping and in the iteration order of the keys. Use #DefaultEncodeOptions() to get the
+// behavior of #Obj.String().
+type EncodeOptions struct {
+	// Prefix is written at the beginning of each new line, if Indent is not empty
+	Prefix string
+
+	// Indent is written once per nesting level. If empty, the output is compact.
+	Indent string
+
+	// SortKeys writes the keys of objects in ascending byte order
+	SortKeys bool
+
+	// EscapeHTML replaces <, > and & in strings with unicode escape sequences, so that the json can be
+	// embedded safely into html
+	EscapeHTML bool
+
+	// OmitNull drops object members whose value is null. Array elements are kept, because their position matters.
+	OmitNull bool
+
+	// OmitEmpty drops object members whose value is an empty object or array, after applying OmitNull to it.
+	OmitEmpty bool
+
+	// FloatFormat is a format of strconv.FormatFloat, like 'f', 'e' or 'g', used for floats with
+	// FloatPrecision. The zero value formats like the json package does.
+	FloatFormat byte
+
+	// FloatPrecision is the precision used with FloatFormat, -1 is the shortest exact representation
+	FloatPrecision int
+
+	// MaxLineWidth keeps objects and arrays on a single line, if they fit into the width. This has only an
+	// effect if Indent is not empty. Zero disables it.
+	MaxLineWidth int
+}
+
+// DefaultEncodeOptions returns the options which correspond to the compact serialization of #Obj.String().
+func DefaultEncodeOptions() EncodeOptions {
+	return EncodeOptions{SortKeys: true, EscapeHTML: true, FloatPrecision: -1}
+}
+
+// An Encoder writes Obj and Arr values as json directly into a writer.
+type Encoder struct {
+	w    io.Writer
+	opts EncodeOptions
+}
+
+// NewEncoder creates a new Encoder which writes into w.
+func NewEncoder(w io.Writer, opts EncodeOptions) *Encoder {
+	return &Encoder{w: w, opts: opts}
+}
+
+// Encode writes the object into the writer.
+func (e *Encoder) Encode(obj Obj) error {
+	_, err := e.encode(obj)
+	return err
+}
+
+// EncodeArr writes the array into the writer.
+func (e *Encoder) EncodeArr(arr Arr) error {
+	_, err := e.encode(arr)
+	return err
+}
+
+func (e *Encoder) encode(v interface{}) (int64, error) {
+	bw := bufio.NewWriter(e.w)
+	cw := &columnWriter{w: bw}
+	enc := &encodeState{opts: e.opts, w: cw}
+	if err := enc.value(v, 0); err != nil {
+		return cw.n, err
+	}
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, nil
+}
+
+// WriteTo serializes the object into the writer and returns the number of written bytes.
+func WriteTo(w io.Writer, obj Obj, opts EncodeOptions) (int64, error) {
+	return NewEncoder(w, opts).encode(obj)
+}
+
+// WriteArrTo serializes the array into the writer and returns the number of written bytes.
+func WriteArrTo(w io.Writer, arr Arr, opts EncodeOptions) (int64, error) {
+	return NewEncoder(w, opts).encode(arr)
+}
+
+// errLineTooLong aborts the measuring of a single line container
+var errLineTooLong = errors.New("line too long")
+
+// columnWriter counts the written bytes and the current column
+type columnWriter struct {
+	w     io.Writer
+	n     int64
+	col   int
+	limit int // if > 0, writing beyond this column fails with errLineTooLong
+}
+
+func (c *columnWriter) Write(p []byte) (int, error) {
+	if i := bytes.LastIndexByte(p, '\n'); i >= 0 {
+		c.col = utf8.RuneCount(p[i+1:])
+	} else {
+		c.col += utf8.RuneCount(p)
+	}
+	if c.limit > 0 && c.col > c.limit {
+		return 0, errLineTooLong
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type encodeState struct {
+	opts   EncodeOptions
+	w      *columnWriter
+	inline bool // true while writing a container on a single line
+	err    error
+}
+
+func (s *encodeState) write(str string) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = s.w.Write([]byte(str))
+}
+
+func (s *encodeState) newline(depth int) {
+	if s.opts.Indent == "" || s.inline {
+		return
+	}
+	s.write("\n")
+	s.write(s.opts.Prefix)
+	for i := 0; i < depth; i++ {
+		s.write(s.opts.Indent)
+	}
+}
+
+func (s *encodeState) value(v interface{}, depth int) error {
+	if isNil(v) {
+		s.write("null")
+		return s.err
+	}
+
+	if obj, ok := toObj(v); ok {
+		return s.container(v, depth, func() { s.object(obj, depth) })
+	}
+
+	if arr, ok := toArr(v); ok {
+		return s.container(v, depth, func() { s.array(arr, depth) })
+	}
+
+	s.primitive(v)
+	return s.err
+}
+
+// container tries to write the container on a single line, if MaxLineWidth allows that
+func (s *encodeState) container(v interface{}, depth int, write func()) error {
+	if s.opts.Indent != "" && s.opts.MaxLineWidth > 0 && !s.inline && s.fitsOnLine(v, depth) {
+		s.inline = true
+		write()
+		s.inline = false
+		return s.err
+	}
+	write()
+	return s.err
+}
+
+// fitsOnLine measures the single line representation without keeping it
+func (s *encodeState) fitsOnLine(v interface{}, depth int) bool {
+	probe := &encodeState{opts: s.opts, inline: true}
+	probe.w = &columnWriter{w: io.Discard, col: s.w.col, limit: s.opts.MaxLineWidth}
+	probe.value(v, depth)
+	return probe.err == nil
+}
+
+func (s *encodeState) object(obj Obj, depth int) {
+	keys := obj.Keys()
+	list := make([]string, 0, keys.Size())
+	for i := 0; i < keys.Size(); i++ {
+		k := keys.Get(i)
+		if s.omit(obj.Get(k)) {
+			continue
+		}
+		list = append(list, k)
+	}
+	if s.opts.SortKeys {
+		sort.Strings(list)
+	}
+
+	if len(list) == 0 {
+		s.write("{}")
+		return
+	}
+
+	s.write("{")
+	for i, k := range list {
+		if i > 0 {
+			s.separator()
+		}
+		s.newline(depth + 1)
+		s.string(k)
+		if s.opts.Indent != "" {
+			s.write(": ")
+		} else {
+			s.write(":")
+		}
+		if s.err != nil {
+			return
+		}
+		s.value(obj.Get(k), depth+1)
+	}
+	s.newline(depth)
+	s.write("}")
+}
+
+func (s *encodeState) array(arr Arr, depth int) {
+	if arr.Size() == 0 {
+		s.write("[]")
+		return
+	}
+
+	s.write("[")
+	for i := 0; i < arr.Size(); i++ {
+		if i > 0 {
+			s.separator()
+		}
+		s.newline(depth + 1)
+		if s.err != nil {
+			return
+		}
+		s.value(arr.Get(i), depth+1)
+	}
+	s.newline(depth)
+	s.write("]")
+}
+
+func (s *encodeState) separator() {
+	if s.inline {
+		s.write(", ")
+		return
+	}
+	s.write(",")
+}
+
+// omit decides if an object member is dropped
+func (s *encodeState) omit(v interface{}) bool {
+	if isNil(v) {
+		return s.opts.OmitNull
+	}
+	if !s.opts.OmitEmpty {
+		return false
+	}
+	if obj, ok := toObj(v); ok {
+		keys := obj.Keys()
+		for i := 0; i < keys.Size(); i++ {
+			if !s.omit(obj.Get(keys.Get(i))) {
+				return false
+			}
+		}
+		return true
+	}
+	if arr, ok := toArr(v); ok {
+		return arr.Size() == 0
+	}
+	return false
+}
+
+func (s *encodeState) primitive(v interface{}) {
+	switch t := v.(type) {
+	case string:
+		s.string(t)
+		return
+	case bool:
+		s.write(strconv.FormatBool(t))
+		return
+	case []byte:
+		s.string(base64.StdEncoding.EncodeToString(t))
+		return
+	case float64:
+		s.float(t, 64)
+		return
+	case float32:
+		s.float(float64(t), 32)
+		return
+	case int, int64, int32, int16, int8, uint, uint64, uint32, uint16, uint8:
+		s.write(fmt.Sprintf("%d", t))
+		return
+	}
+
+	// anything else, like times or structs, is delegated to the json package
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(s.opts.EscapeHTML)
+	if err := enc.Encode(v); err != nil {
+		if s.err == nil {
+			s.err = err
+		}
+		return
+	}
+	s.write(string(bytes.TrimRight(buf.Bytes(), "\n")))
+}
+
+// float writes like the json package or with the configured format
+func (s *encodeState) float(f float64, bits int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		if s.err == nil {
+			s.err = fmt.Errorf("unsupported float value %v", f)
+		}
+		return
+	}
+
+	if s.opts.FloatFormat != 0 {
+		s.write(strconv.FormatFloat(f, s.opts.FloatFormat, s.opts.FloatPrecision, bits))
+		return
+	}
+
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 && (bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21)) {
+		format = 'e'
+	}
+	str := strconv.FormatFloat(f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(str)
+		if n >= 4 && str[n-4] == 'e' && str[n-3] == '-' && str[n-2] == '0' {
+			str = str[:n-2] + str[n-1:]
+		}
+	}
+	s.write(str)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// string escapes like the json package does
+func (s *encodeState) string(str string) {
+	buf := make([]byte, 0, len(str)+2)
+	buf = append(buf, '"')
+	for i := 0; i < len(str); {
+		c := str[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf = append(buf, '\\', c)
+			case c == '\n':
+				buf = append(buf, '\\', 'n')
+			case c == '\r':
+				buf = append(buf, '\\', 'r')
+			case c == '\t':
+				buf = append(buf, '\\', 't')
+			case c < 0x20 || s.opts.EscapeHTML && (c == '<' || c == '>' || c == '&'):
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			default:
+				buf = append(buf, c)
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(str[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			buf = append(buf, `\ufffd`...)
+		case r == '\u2028' || r == '\u2029':
+			buf = append(buf, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+		default:
+			buf = append(buf, str[i:i+size]...)
+		}
+		i += size
+	}
+	buf = append(buf, '"')
+	s.write(string(buf))
+}
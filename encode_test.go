@@ -0,0 +1,77 @@
+package xobj
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	obj, err := Parse([]byte(json0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	n, err := WriteTo(buf, obj, DefaultEncodeOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != obj.String() || n != int64(buf.Len()) {
+		t.Fatal("unexpected", buf.String(), n)
+	}
+
+	obj = NewObj().
+		PutString("html", "<b>&</b>").
+		Put("null", nil).
+		PutObject("empty", NewObj().Put("null", nil)).
+		PutArray("list", NewArr().AddInt64(1).AddFloat64(0.000001).AddFloat64(1.5).Put(0, nil)).
+		PutFloat64("big", 1e21)
+
+	buf.Reset()
+	_, err = WriteTo(buf, obj, EncodeOptions{SortKeys: true, OmitNull: true, OmitEmpty: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != `{"big":1e+21,"html":"<b>&</b>","list":[null,0.000001,1.5]}` {
+		t.Fatal("unexpected", buf.String())
+	}
+
+	buf.Reset()
+	_, err = WriteTo(buf, obj, EncodeOptions{SortKeys: true, EscapeHTML: true, Indent: "  ", FloatFormat: 'f', FloatPrecision: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "big": 1000000000000000000000.00,
+  "empty": {
+    "null": null
+  },
+  "html": "\u003cb\u003e\u0026\u003c/b\u003e",
+  "list": [
+    null,
+    0.00,
+    1.50
+  ],
+  "null": null
+}`
+	if buf.String() != expected {
+		t.Fatal("unexpected", buf.String())
+	}
+}
+
+func TestEncodeOptions_MaxLineWidth(t *testing.T) {
+	obj := NewObj().
+		PutArray("short", NewArr().AddInt64(1).AddInt64(2)).
+		PutArray("long", NewArr().AddString(strings.Repeat("x", 30)).AddString(strings.Repeat("y", 30)))
+
+	buf := &bytes.Buffer{}
+	err := NewEncoder(buf, EncodeOptions{SortKeys: true, Indent: "\t", MaxLineWidth: 40}).Encode(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n\t\"long\": [\n\t\t\"" + strings.Repeat("x", 30) + "\",\n\t\t\"" + strings.Repeat("y", 30) + "\"\n\t],\n\t\"short\": [1, 2]\n}"
+	if buf.String() != expected {
+		t.Fatal("unexpected", buf.String())
+	}
+}
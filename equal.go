@@ -0,0 +1,386 @@
+package xobj
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+	"strconv"
+)
+
+// Clone creates a deep copy of the given object. Nested Obj and Arr values are copied into an Object or
+// an *Array, raw maps and slices stay raw maps and slices. The source is never modified.
+func Clone(obj Obj) Obj {
+	if obj == nil {
+		return nil
+	}
+	return cloneObj(obj)
+}
+
+// CloneArr creates a deep copy of the given array, see also #Clone().
+func CloneArr(arr Arr) Arr {
+	if arr == nil {
+		return nil
+	}
+	return cloneArr(arr)
+}
+
+func cloneObj(obj Obj) Object {
+	res := Object{}
+	keys := obj.Keys()
+	for i := 0; i < keys.Size(); i++ {
+		k := keys.Get(i)
+		res[k] = cloneValue(obj.Get(k))
+	}
+	return res
+}
+
+func cloneArr(arr Arr) *Array {
+	res := make(Array, arr.Size())
+	for i := range res {
+		res[i] = cloneValue(arr.Get(i))
+	}
+	return &res
+}
+
+func cloneValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return map[string]interface{}(cloneObj(Object(t)))
+	case []interface{}:
+		tmp := Array(t)
+		return []interface{}(*cloneArr(&tmp))
+	case *[]interface{}:
+		tmp := []interface{}(*cloneArr((*Array)(t)))
+		return &tmp
+	case []byte:
+		return copyBytes(t)
+	case Obj:
+		return cloneObj(t)
+	case Arr:
+		return cloneArr(t)
+	case wrapper:
+		tmp := Array(t.Unwrap())
+		return []interface{}(*cloneArr(&tmp))
+	}
+	return v
+}
+
+// EqualOptions configure the comparison of #EqualWith().
+type EqualOptions struct {
+	// FloatTolerance is the maximum absolute difference of two numbers, which are still considered equal
+	FloatTolerance float64
+
+	// IgnoreArrayOrder compares arrays as multisets, so that [1,2] equals [2,1]
+	IgnoreArrayOrder bool
+}
+
+// Equal compares two objects structurally. Wrapped and raw containers are equal if their content is
+// equal and all numeric types are compared by their value, so that int64(1) equals float64(1). Byte slices
+// are equal to their standard base64 string, which is their json representation. Strings are never equal
+// to numbers or booleans.
+func Equal(a, b Obj) bool {
+	return EqualWith(a, b, EqualOptions{})
+}
+
+// EqualWith is like #Equal() but applies the given options.
+func EqualWith(a, b Obj, opts EqualOptions) bool {
+	return equalValues(a, b, opts)
+}
+
+// EqualValues compares any two values, like they would be compared as members of an object.
+func EqualValues(a, b interface{}, opts EqualOptions) bool {
+	return equalValues(a, b, opts)
+}
+
+func equalValues(a, b interface{}, opts EqualOptions) bool {
+	if a == nil || b == nil {
+		return isNil(a) && isNil(b)
+	}
+
+	// each kind is checked for both values, so that the result does not depend on the order
+	oa, okA := toObj(a)
+	ob, okB := toObj(b)
+	if okA || okB {
+		return okA && okB && equalObj(oa, ob, opts)
+	}
+
+	aa, okA := toArr(a)
+	ab, okB := toArr(b)
+	if okA || okB {
+		if !okA || !okB {
+			return false
+		}
+		if opts.IgnoreArrayOrder {
+			return equalArrUnordered(aa, ab, opts)
+		}
+		return equalArr(aa, ab, opts)
+	}
+
+	na, okA := toNumber(a)
+	nb, okB := toNumber(b)
+	if okA || okB {
+		return okA && okB && na.equal(nb, opts.FloatTolerance)
+	}
+
+	ba, okA := a.(bool)
+	bb, okB := b.(bool)
+	if okA || okB {
+		return okA && okB && ba == bb
+	}
+
+	sa, sb := stringOf(a), stringOf(b)
+	if sa != notAString || sb != notAString {
+		return sa == sb
+	}
+
+	return ToString(a) == ToString(b)
+}
+
+// isNil also treats nil pointers to slices as nil
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	if p, ok := v.(*[]interface{}); ok {
+		return p == nil
+	}
+	return false
+}
+
+// stringOf returns strings and the base64 representation of byte slices or a marker which is
+// never equal to a string.
+func stringOf(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []byte:
+		return base64.StdEncoding.EncodeToString(t)
+	}
+	return notAString
+}
+
+// notAString is returned by #stringOf() for all other values
+const notAString = "\x00not a string"
+
+func equalObj(a, b Obj, opts EqualOptions) bool {
+	ka := a.Keys()
+	if ka.Size() != b.Keys().Size() {
+		return false
+	}
+	for i := 0; i < ka.Size(); i++ {
+		k := ka.Get(i)
+		if !b.Has(k) {
+			return false
+		}
+		if !equalValues(a.Get(k), b.Get(k), opts) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalArr(a, b Arr, opts EqualOptions) bool {
+	if a.Size() != b.Size() {
+		return false
+	}
+	for i := 0; i < a.Size(); i++ {
+		if !equalValues(a.Get(i), b.Get(i), opts) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalArrUnordered finds a partner for each element, which is quadratic but fine for documents
+func equalArrUnordered(a, b Arr, opts EqualOptions) bool {
+	if a.Size() != b.Size() {
+		return false
+	}
+	used := make([]bool, b.Size())
+	for i := 0; i < a.Size(); i++ {
+		found := false
+		for j := 0; j < b.Size(); j++ {
+			if !used[j] && equalValues(a.Get(i), b.Get(j), opts) {
+				used[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// number keeps integers exact and uses floats otherwise
+type number struct {
+	isInt bool
+	i     int64
+	f     float64
+}
+
+func (n number) float() float64 {
+	if n.isInt {
+		return float64(n.i)
+	}
+	return n.f
+}
+
+// equal compares exactly without a tolerance. Floats without a fraction are already integers, see
+// #floatNumber(), so an integer never equals a float, e.g. 2^53+1 does not equal the float 2^53 into which
+// it would be rounded. This is the same normalization as #Hash() uses.
+func (n number) equal(o number, tolerance float64) bool {
+	if tolerance == 0 {
+		if n.isInt || o.isInt {
+			return n.isInt == o.isInt && n.i == o.i
+		}
+		return n.f == o.f
+	}
+	a, b := n.float(), o.float()
+	if a == b {
+		return true
+	}
+	return math.Abs(a-b) <= tolerance
+}
+
+// toNumber normalizes all numeric go types. Strings are not numbers.
+func toNumber(v interface{}) (number, bool) {
+	switch t := v.(type) {
+	case float64:
+		return floatNumber(t), true
+	case float32:
+		return floatNumber(float64(t)), true
+	case int:
+		return number{isInt: true, i: int64(t)}, true
+	case int64:
+		return number{isInt: true, i: t}, true
+	case int32:
+		return number{isInt: true, i: int64(t)}, true
+	case int16:
+		return number{isInt: true, i: int64(t)}, true
+	case int8:
+		return number{isInt: true, i: int64(t)}, true
+	case uint:
+		return uintNumber(uint64(t)), true
+	case uint64:
+		return uintNumber(t), true
+	case uint32:
+		return number{isInt: true, i: int64(t)}, true
+	case uint16:
+		return number{isInt: true, i: int64(t)}, true
+	case uint8:
+		return number{isInt: true, i: int64(t)}, true
+	case interface{ Float64() (float64, error) }: // e.g. json.Number
+		if i, ok := v.(interface{ Int64() (int64, error) }); ok {
+			if n, err := i.Int64(); err == nil {
+				return number{isInt: true, i: n}, true
+			}
+		}
+		if f, err := t.Float64(); err == nil {
+			return floatNumber(f), true
+		}
+	}
+	return number{}, false
+}
+
+// floatNumber represents floats without a fraction as integers, so that equal values are hashed equally
+func floatNumber(f float64) number {
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return number{isInt: true, i: int64(f)}
+	}
+	return number{f: f}
+}
+
+func uintNumber(u uint64) number {
+	if u > math.MaxInt64 {
+		return number{f: float64(u)}
+	}
+	return number{isInt: true, i: int64(u)}
+}
+
+// Hash calculates a stable 64 bit content hash, which is independent of the key order, the numeric types
+// and wrapped or raw containers. Values which are #Equal() have the same hash, so it can be used as a cache key.
+// The hash does not respect the options of #EqualWith().
+func Hash(obj Obj) uint64 {
+	h := fnv.New64a()
+	hashValue(h, obj)
+	return h.Sum64()
+}
+
+// HashArr is like #Hash() but for an array.
+func HashArr(arr Arr) uint64 {
+	h := fnv.New64a()
+	hashValue(h, arr)
+	return h.Sum64()
+}
+
+// type tags avoid collisions between different types with the same bytes
+const (
+	hashNull byte = iota
+	hashBool
+	hashInt
+	hashFloat
+	hashString
+	hashObj
+	hashArr
+	hashOther
+)
+
+func hashValue(h hash.Hash64, v interface{}) {
+	var buf [8]byte
+	writeUint := func(tag byte, u uint64) {
+		h.Write([]byte{tag})
+		binary.BigEndian.PutUint64(buf[:], u)
+		h.Write(buf[:])
+	}
+	writeString := func(tag byte, s string) {
+		writeUint(tag, uint64(len(s)))
+		h.Write([]byte(s))
+	}
+
+	if isNil(v) {
+		h.Write([]byte{hashNull})
+		return
+	}
+
+	if obj, ok := toObj(v); ok {
+		keys := sortedKeys(obj)
+		writeUint(hashObj, uint64(len(keys)))
+		for _, k := range keys {
+			writeString(hashString, k)
+			hashValue(h, obj.Get(k))
+		}
+		return
+	}
+
+	if arr, ok := toArr(v); ok {
+		writeUint(hashArr, uint64(arr.Size()))
+		for i := 0; i < arr.Size(); i++ {
+			hashValue(h, arr.Get(i))
+		}
+		return
+	}
+
+	if n, ok := toNumber(v); ok {
+		if n.isInt {
+			writeUint(hashInt, uint64(n.i))
+		} else {
+			writeUint(hashFloat, math.Float64bits(n.f))
+		}
+		return
+	}
+
+	switch t := v.(type) {
+	case bool:
+		writeString(hashBool, strconv.FormatBool(t))
+	case string:
+		writeString(hashString, t)
+	case []byte:
+		writeString(hashString, base64.StdEncoding.EncodeToString(t))
+	default:
+		writeString(hashOther, ToString(v))
+	}
+}
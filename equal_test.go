@@ -0,0 +1,96 @@
+package xobj
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClone(t *testing.T) {
+	obj, err := Parse([]byte(json0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpy := Clone(obj)
+	if !Equal(obj, cpy) {
+		t.Fatal("expected equal")
+	}
+
+	cpy.OptArray("list").OptObject(6).PutString("k", "changed")
+	if obj.String() == cpy.String() {
+		t.Fatal("clone shares data")
+	}
+	if _, ok := obj.Get("list").([]interface{}); !ok {
+		t.Fatal("source has been modified")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	parsed, err := Parse([]byte(`{"i":1,"f":2.5,"s":"x","b":true,"n":null,"l":[1,{"a":[]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	built := NewObj().
+		PutInt64("i", 1).
+		PutFloat64("f", 2.5).
+		PutString("s", "x").
+		PutBool("b", true).
+		Put("n", nil).
+		PutArray("l", NewArr().AddInt64(1).AddObject(NewObj().PutArray("a", NewArr())))
+
+	if !Equal(parsed, built) || !Equal(built, parsed) {
+		t.Fatal("expected equal")
+	}
+	if Hash(parsed) != Hash(built) {
+		t.Fatal("expected same hash")
+	}
+
+	built.PutString("i", "1")
+	if Equal(parsed, built) {
+		t.Fatal("string must not equal number")
+	}
+	if Hash(parsed) == Hash(built) {
+		t.Fatal("expected different hash")
+	}
+
+	a := NewObj().PutArray("l", NewArr().AddFloat64(1.0001).AddInt64(2))
+	b := NewObj().PutArray("l", NewArr().AddInt64(2).AddInt64(1))
+	if Equal(a, b) || EqualWith(a, b, EqualOptions{IgnoreArrayOrder: true}) {
+		t.Fatal("expected not equal")
+	}
+	if !EqualWith(a, b, EqualOptions{IgnoreArrayOrder: true, FloatTolerance: 0.001}) {
+		t.Fatal("expected equal")
+	}
+
+	if !Equal(NewObj().PutBytes("b", []byte("hi")), NewObj().PutString("b", "aGk=")) {
+		t.Fatal("expected bytes equal base64")
+	}
+
+	// integers beyond 2^53 are compared exactly, like they are hashed
+	big := NewObj().PutInt64("n", 1<<53+1)
+	rounded := NewObj().PutFloat64("n", float64(1<<53+1))
+	if Equal(big, rounded) || Hash(big) == Hash(rounded) {
+		t.Fatal("expected not equal")
+	}
+	exact := NewObj().PutInt64("n", 1<<53)
+	if !Equal(exact, rounded) || Hash(exact) != Hash(rounded) {
+		t.Fatal("expected equal")
+	}
+}
+
+func TestEqualValues_Symmetric(t *testing.T) {
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	values := []interface{}{nil, int64(1), 1.0, "1", true, "true", []byte("hi"), "aGk=", ts, ts.Format(time.RFC3339),
+		NewObj(), Object{}, NewArr(), []interface{}{}}
+	for _, x := range values {
+		for _, y := range values {
+			if EqualValues(x, y, EqualOptions{}) != EqualValues(y, x, EqualOptions{}) {
+				t.Fatalf("unexpected %#v %#v", x, y)
+			}
+		}
+	}
+	if !EqualValues(int64(1), 1.0, EqualOptions{}) || !EqualValues("aGk=", []byte("hi"), EqualOptions{}) ||
+		EqualValues("true", true, EqualOptions{}) || EqualValues(ts.Format(time.RFC3339), ts, EqualOptions{}) {
+		t.Fatal("unexpected")
+	}
+}
@@ -0,0 +1,136 @@
+package xobj
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	// ErrNotFound is the kind of error, if an Obj has no such key.
+	ErrNotFound = errors.New("not found")
+
+	// ErrOutOfBounds is the kind of error, if an index is not within the size of an Arr.
+	ErrOutOfBounds = errors.New("out of bounds")
+
+	// ErrTypeMismatch is the kind of error, if a value has a type which cannot be converted at all, like
+	// an Obj into a float.
+	ErrTypeMismatch = errors.New("type mismatch")
+
+	// ErrConversion is the kind of error, if a value has a convertible type but not a convertible value,
+	// like the string "hello" into a float.
+	ErrConversion = errors.New("conversion failed")
+)
+
+// An Error describes a failed access and is returned by all accessors of Obj and Arr. Use errors.Is with
+// ErrNotFound, ErrOutOfBounds, ErrTypeMismatch or ErrConversion to check the kind of problem and
+// errors.As to inspect the details.
+type Error struct {
+	// Kind is one of ErrNotFound, ErrOutOfBounds, ErrTypeMismatch or ErrConversion
+	Kind error
+
+	// Path is the path to the failed value. The last segment is the key or index of the access. Accessing
+	// a value directly contains only a single segment, but functions which navigate nested
+	// values, like #Lookup() and #Get(), contain the entire path from the root. Object and Array do not
+	// know their parents, so chained calls like obj.OptObject("a").AsString("b") report only "b". Only
+	// SyncObj and ObservableObj, which address their nested values by path, report it entirely.
+	Path Path
+
+	// Type is the actual type of the value, which is nil if the value was not found
+	Type reflect.Type
+
+	// Target describes the requested type, like int64 or object
+	Target string
+
+	// Size is the size of the array, for an ErrOutOfBounds
+	Size int
+
+	// Err is the cause, e.g. the parser error of a failed conversion
+	Err error
+}
+
+func (e *Error) Error() string {
+	var msg string
+	switch e.Kind {
+	case ErrNotFound:
+		msg = "unknown name"
+	case ErrOutOfBounds:
+		msg = fmt.Sprintf("out of bounds, having %d", e.Size)
+	case ErrTypeMismatch:
+		msg = fmt.Sprintf("cannot use '%v' as %s", e.Type, e.Target)
+	case ErrConversion:
+		msg = fmt.Sprintf("cannot convert '%v' into %s", e.Type, e.Target)
+	default:
+		msg = fmt.Sprintf("%v", e.Kind)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if len(e.Path) == 0 {
+		return msg
+	}
+	return e.Path.String() + ": " + msg
+}
+
+// Unwrap returns the cause, if any
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true, if the target is the Kind of this error
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// Key returns the last path segment, if it is the key of an Obj
+func (e *Error) Key() (string, bool) {
+	if len(e.Path) == 0 {
+		return "", false
+	}
+	key, ok := e.Path[len(e.Path)-1].(string)
+	return key, ok
+}
+
+// Index returns the last path segment, if it is the index of an Arr
+func (e *Error) Index() (int, bool) {
+	if len(e.Path) == 0 {
+		return 0, false
+	}
+	idx, ok := e.Path[len(e.Path)-1].(int)
+	return idx, ok
+}
+
+func unknownFieldName(name string) error {
+	return &Error{Kind: ErrNotFound, Path: Path{name}}
+}
+
+func outOfBounds(slice []interface{}, idx int) error {
+	return outOfBoundsAt(idx, len(slice))
+}
+
+func outOfBoundsAt(idx int, size int) error {
+	return &Error{Kind: ErrOutOfBounds, Path: Path{idx}, Size: size}
+}
+
+func typeMismatch(v interface{}, target string) error {
+	return &Error{Kind: ErrTypeMismatch, Type: reflect.TypeOf(v), Target: target}
+}
+
+func conversionFailed(v interface{}, target string, cause error) error {
+	return &Error{Kind: ErrConversion, Type: reflect.TypeOf(v), Target: target, Err: cause}
+}
+
+// withPath prepends the given segments to the path of an *Error. Other errors are returned as is.
+func withPath(prefix Path, err error) error {
+	if err == nil || len(prefix) == 0 {
+		return err
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		return err
+	}
+	cpy := *e
+	cpy.Path = make(Path, 0, len(prefix)+len(e.Path))
+	cpy.Path = append(append(cpy.Path, prefix...), e.Path...)
+	return &cpy
+}
@@ -0,0 +1,111 @@
+package xobj
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestError_Is(t *testing.T) {
+	obj := NewObj().
+		PutString("text", "hello").
+		PutObject("obj", NewObj()).
+		PutArray("arr", NewArr().AddInt64(1))
+
+	_, err := obj.AsInt64("missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal("unexpected", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatal("expected *Error", err)
+	}
+	if key, ok := e.Key(); !ok || key != "missing" {
+		t.Fatal("unexpected", key)
+	}
+
+	_, err = obj.AsInt64("text")
+	if !errors.Is(err, ErrConversion) || !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatal("unexpected", err)
+	}
+
+	_, err = obj.AsArray("obj")
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatal("unexpected", err)
+	}
+
+	_, err = obj.OptArray("arr").AsBool(3)
+	if !errors.Is(err, ErrOutOfBounds) || !errors.As(err, &e) || e.Size != 1 {
+		t.Fatal("unexpected", err)
+	}
+	if idx, ok := e.Index(); !ok || idx != 3 {
+		t.Fatal("unexpected", idx)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	obj, err := Parse([]byte(json0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := Lookup(obj, Path{"list", 6, "k"})
+	if err != nil || v != "v" {
+		t.Fatal("unexpected", v, err)
+	}
+
+	_, err = Lookup(obj, Path{"list", 6, "x"})
+	var e *Error
+	if !errors.As(err, &e) || !errors.Is(err, ErrNotFound) || e.Path.String() != "list[6].x" {
+		t.Fatal("unexpected", err)
+	}
+
+	_, err = Lookup(obj, Path{"list", 9})
+	if !errors.Is(err, ErrOutOfBounds) || err.Error() != "list[9]: out of bounds, having 8" {
+		t.Fatal("unexpected", err)
+	}
+
+	_, err = Lookup(obj, Path{"hello", 0})
+	if !errors.As(err, &e) || !errors.Is(err, ErrTypeMismatch) || e.Path.String() != "hello" {
+		t.Fatal("unexpected", err)
+	}
+}
+
+func TestError_Path(t *testing.T) {
+	obj := NewObj().PutObject("a", NewObj().PutString("b", "x"))
+
+	// chained calls only know the leaf
+	_, err := obj.OptObject("a").AsInt64("b")
+	var e *Error
+	if !errors.As(err, &e) || e.Path.String() != "b" {
+		t.Fatal("unexpected", err)
+	}
+
+	_, err = Get[int64](obj, "a.b")
+	if !errors.As(err, &e) || e.Path.String() != "a.b" {
+		t.Fatal("unexpected", err)
+	}
+
+	_, err = NewSyncObj(obj).OptObject("a").AsInt64("b")
+	if !errors.As(err, &e) || e.Path.String() != "a.b" {
+		t.Fatal("unexpected", err)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	for _, str := range []string{"", "a", "a.b[0].c", "[1][2]", `a["b.c"][3]`, `["\"quoted\""]`} {
+		p, err := ParsePath(str)
+		if err != nil {
+			t.Fatal(str, err)
+		}
+		if p.String() != str {
+			t.Fatal("unexpected", p.String(), "from", str)
+		}
+	}
+
+	for _, str := range []string{"a.", ".a", "a..b", "a[x]", "a[-1]", "a[0", `a["b]`, "a[0]b"} {
+		if _, err := ParsePath(str); err == nil {
+			t.Fatal("expected error for", str)
+		}
+	}
+}
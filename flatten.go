@@ -0,0 +1,309 @@
+package xobj
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ArrayNotation defines how array indices are written into flattened keys.
+type ArrayNotation int
+
+const (
+	// ArrayBrackets writes indices in brackets, like a.b[0].c
+	ArrayBrackets ArrayNotation = iota
+	// ArrayDotted writes indices like keys, like a.b.0.c
+	ArrayDotted
+)
+
+// FlattenOptions configure #Flatten() and #Unflatten(). The zero value uses the notation of #Path.String().
+type FlattenOptions struct {
+	// Separator is written between the keys of nested objects, the default is a dot
+	Separator string
+
+	// Arrays defines the notation of array indices
+	Arrays ArrayNotation
+
+	// MaxIndex is the largest array index which is accepted by #Unflatten(), the default is 10000. Missing
+	// elements are filled with null, so without a limit a single key like a[2000000000] of untrusted form
+	// or environment input would allocate gigabytes.
+	MaxIndex int
+}
+
+// DefaultMaxIndex is the default of FlattenOptions.MaxIndex
+const DefaultMaxIndex = 10000
+
+func (o FlattenOptions) separator() string {
+	if o.Separator == "" {
+		return "."
+	}
+	return o.Separator
+}
+
+func (o FlattenOptions) maxIndex() int {
+	if o.MaxIndex <= 0 {
+		return DefaultMaxIndex
+	}
+	return o.MaxIndex
+}
+
+// pathNotation is true, if the keys are exactly the notation of #Path.String(), including quoted keys
+func (o FlattenOptions) pathNotation() bool {
+	return o.separator() == "." && o.Arrays == ArrayBrackets
+}
+
+// Flatten returns an object, whose keys are the paths of all primitive values, like a.b[0].c. Empty objects
+// and arrays are kept as values, so that #Unflatten() restores them. With the default options, keys which
+// contain special characters are quoted like #Path.String() does, otherwise keys are written as is and
+// flattening is only reversible, if the keys contain no separator.
+func Flatten(obj Obj, opts FlattenOptions) Object {
+	res := Object{}
+	flatten(res, Path{}, obj, opts)
+	return res
+}
+
+func flatten(res Object, path Path, v interface{}, opts FlattenOptions) {
+	if obj, ok := toObj(v); ok && !isNil(v) {
+		keys := sortedKeys(obj)
+		if len(keys) == 0 && len(path) > 0 {
+			res[flatKey(path, opts)] = Object{}
+		}
+		for _, k := range keys {
+			flatten(res, path.Child(k), obj.Get(k), opts)
+		}
+		return
+	}
+
+	if arr, ok := toArr(v); ok && !isNil(v) {
+		if arr.Size() == 0 {
+			res[flatKey(path, opts)] = &Array{}
+		}
+		for i := 0; i < arr.Size(); i++ {
+			flatten(res, path.Child(i), arr.Get(i), opts)
+		}
+		return
+	}
+
+	res[flatKey(path, opts)] = v
+}
+
+func flatKey(path Path, opts FlattenOptions) string {
+	if opts.pathNotation() {
+		return path.String()
+	}
+	sb := &strings.Builder{}
+	for i, seg := range path {
+		switch t := seg.(type) {
+		case int:
+			if opts.Arrays == ArrayBrackets {
+				sb.WriteString("[" + strconv.Itoa(t) + "]")
+				continue
+			}
+			if i > 0 {
+				sb.WriteString(opts.separator())
+			}
+			sb.WriteString(strconv.Itoa(t))
+		default:
+			if i > 0 {
+				sb.WriteString(opts.separator())
+			}
+			sb.WriteString(fmt.Sprintf("%v", t))
+		}
+	}
+	return sb.String()
+}
+
+// parseFlatKey splits a key into segments. With dotted arrays, all segments are strings and the arrays
+// are inferred later.
+func parseFlatKey(key string, opts FlattenOptions) (Path, error) {
+	if opts.pathNotation() {
+		return ParsePath(key)
+	}
+
+	var res Path
+	for _, part := range strings.Split(key, opts.separator()) {
+		if opts.Arrays == ArrayDotted {
+			res = append(res, part)
+			continue
+		}
+		name := part
+		var indices []interface{}
+		for strings.HasSuffix(name, "]") {
+			open := strings.LastIndexByte(name, '[')
+			if open < 0 {
+				break
+			}
+			idx, err := strconv.Atoi(name[open+1 : len(name)-1])
+			if err != nil || idx < 0 {
+				return nil, fmt.Errorf("invalid index in key '%s'", key)
+			}
+			indices = append([]interface{}{idx}, indices...)
+			name = name[:open]
+		}
+		if name != "" || len(indices) == 0 {
+			res = append(res, name)
+		}
+		res = append(res, indices...)
+	}
+	return res, nil
+}
+
+// flatNode collects the values of a subtree while unflattening
+type flatNode struct {
+	children map[interface{}]*flatNode
+	leaf     bool
+	value    interface{}
+	root     bool // the root is always an object
+}
+
+// Unflatten rebuilds the nested document from flattened keys, see #Flatten(). Segments in brackets are
+// array indices and missing elements become null. With dotted arrays, an object becomes an array if all its
+// keys are the indices 0 to n-1. Keys which are both a value and a parent of other values are an error.
+func Unflatten(flat Obj, opts FlattenOptions) (Obj, error) {
+	root := &flatNode{children: map[interface{}]*flatNode{}, root: true}
+	for _, key := range sortedKeys(flat) {
+		path, err := parseFlatKey(key, opts)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return nil, fmt.Errorf("invalid empty key")
+		}
+		if _, ok := path[0].(string); !ok {
+			return nil, fmt.Errorf("key '%s' must start with a name", key)
+		}
+		if err := root.insert(key, path, flat.Get(key)); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := root.build(opts)
+	if err != nil {
+		return nil, err
+	}
+	return res.(Object), nil
+}
+
+func (n *flatNode) insert(key string, path Path, value interface{}) error {
+	cur := n
+	for _, seg := range path {
+		if cur.leaf {
+			return fmt.Errorf("key '%s' conflicts with a value of a parent", key)
+		}
+		if cur.children == nil {
+			cur.children = map[interface{}]*flatNode{}
+		}
+		next := cur.children[seg]
+		if next == nil {
+			next = &flatNode{}
+			cur.children[seg] = next
+		}
+		cur = next
+	}
+
+	// empty containers are just placeholders for their parent
+	if !isNil(value) && isEmptyContainer(value) {
+		if cur.children == nil {
+			cur.children = map[interface{}]*flatNode{}
+			if arr, ok := toArr(value); ok && arr.Size() == 0 {
+				cur.value = &Array{}
+			}
+		}
+		return nil
+	}
+
+	if cur.leaf || len(cur.children) > 0 {
+		return fmt.Errorf("key '%s' conflicts with other keys", key)
+	}
+	cur.leaf = true
+	cur.value = value
+	return nil
+}
+
+func isEmptyContainer(v interface{}) bool {
+	if obj, ok := toObj(v); ok {
+		return obj.Keys().Size() == 0
+	}
+	if arr, ok := toArr(v); ok {
+		return arr.Size() == 0
+	}
+	return false
+}
+
+func (n *flatNode) build(opts FlattenOptions) (interface{}, error) {
+	if n.leaf {
+		return n.value, nil
+	}
+
+	var ints []int
+	var strs []string
+	for seg := range n.children {
+		switch t := seg.(type) {
+		case int:
+			ints = append(ints, t)
+		case string:
+			strs = append(strs, t)
+		}
+	}
+	if len(ints) > 0 && len(strs) > 0 {
+		return nil, fmt.Errorf("cannot mix indices %v and keys %v", ints, strs)
+	}
+
+	// an explicit empty array or indices
+	if _, ok := n.value.(*Array); ok && len(n.children) == 0 || len(ints) > 0 {
+		sort.Ints(ints)
+		size := 0
+		if len(ints) > 0 {
+			last := ints[len(ints)-1]
+			if last > opts.maxIndex() {
+				return nil, fmt.Errorf("index %d exceeds the maximum index %d", last, opts.maxIndex())
+			}
+			size = last + 1
+		}
+		res := make(Array, size)
+		for _, idx := range ints {
+			v, err := n.children[idx].build(opts)
+			if err != nil {
+				return nil, err
+			}
+			res[idx] = v
+		}
+		return &res, nil
+	}
+
+	if opts.Arrays == ArrayDotted && !n.root && len(strs) > 0 && isIndexSequence(strs) {
+		res := make(Array, len(strs))
+		for i := range res {
+			v, err := n.children[strconv.Itoa(i)].build(opts)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = v
+		}
+		return &res, nil
+	}
+
+	res := Object{}
+	for _, k := range strs {
+		v, err := n.children[k].build(opts)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = v
+	}
+	return res, nil
+}
+
+// isIndexSequence is true, if the keys are exactly 0 to n-1 without leading zeros
+func isIndexSequence(keys []string) bool {
+	seen := make([]bool, len(keys))
+	for _, k := range keys {
+		idx, err := strconv.Atoi(k)
+		if err != nil || idx < 0 || idx >= len(keys) || strconv.Itoa(idx) != k || seen[idx] {
+			return false
+		}
+		seen[idx] = true
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package xobj
+
+import (
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	obj := parseObj(t, `{"db":{"host":"localhost","ports":[80,443]},"a.b":{"c":true},"empty":{},"list":[],"nested":[[1],{"x":null}]}`)
+
+	flat := Flatten(obj, FlattenOptions{})
+	expected := `{"[\"a.b\"].c":true,"db.host":"localhost","db.ports[0]":80,"db.ports[1]":443,"empty":{},"list":[],"nested[0][0]":1,"nested[1].x":null}`
+	if flat.String() != expected {
+		t.Fatal("unexpected", flat.String())
+	}
+
+	res, err := Unflatten(flat, FlattenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(obj, res) {
+		t.Fatal("unexpected", res)
+	}
+}
+
+func TestFlatten_Options(t *testing.T) {
+	obj := parseObj(t, `{"db":{"host":"localhost","ports":[80,443]},"ids":{"0":"a","2":"b"}}`)
+	opts := FlattenOptions{Separator: "__", Arrays: ArrayDotted}
+
+	flat := Flatten(obj, opts)
+	expected := `{"db__host":"localhost","db__ports__0":80,"db__ports__1":443,"ids__0":"a","ids__2":"b"}`
+	if flat.String() != expected {
+		t.Fatal("unexpected", flat.String())
+	}
+
+	res, err := Unflatten(flat, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(obj, res) {
+		t.Fatal("unexpected", res)
+	}
+
+	res, err = Unflatten(Object{"a_b[2]": 1}, FlattenOptions{Separator: "_"})
+	if err != nil || res.String() != `{"a":{"b":[null,null,1]}}` {
+		t.Fatal("unexpected", res, err)
+	}
+}
+
+func TestUnflatten_Conflict(t *testing.T) {
+	if _, err := Unflatten(Object{"a": 1, "a.b": 2}, FlattenOptions{}); err == nil {
+		t.Fatal("expected conflict")
+	}
+	if _, err := Unflatten(Object{"a[0]": 1, "a.b": 2}, FlattenOptions{}); err == nil {
+		t.Fatal("expected conflict")
+	}
+}
+
+func TestUnflatten_MaxIndex(t *testing.T) {
+	if _, err := Unflatten(Object{"a[2000000000]": 1}, FlattenOptions{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := Unflatten(Object{"a.b.2000000000": 1}, FlattenOptions{Arrays: ArrayDotted}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Unflatten(Object{"a[11]": 1}, FlattenOptions{MaxIndex: 10}); err == nil {
+		t.Fatal("expected error")
+	}
+	res, err := Unflatten(Object{"a[2]": 1}, FlattenOptions{MaxIndex: 2})
+	if err != nil || res.String() != `{"a":[null,null,1]}` {
+		t.Fatal("unexpected", res, err)
+	}
+}
+
+func TestUnflatten_Root(t *testing.T) {
+	res, err := Unflatten(Object{"0": "a", "1": "b"}, FlattenOptions{Arrays: ArrayDotted})
+	if err != nil || res.String() != `{"0":"a","1":"b"}` {
+		t.Fatal("unexpected", res, err)
+	}
+}
@@ -0,0 +1,383 @@
+package xobj
+
+import (
+	"fmt"
+	"iter"
+	"time"
+)
+
+// This file contains a generic layer on top of Obj and Arr. Generic functions and iterators cannot be
+// exported by gomobile, so they are only helpers and the non-generic interfaces stay the contract.
+
+// Get resolves the path (see #ParsePath()) beginning at the given object and converts the value into T, using the
+// same heuristics as the typed accessors. Supported types are int, int64, float64, bool, string,
+// time.Time, time.Duration, []byte, Obj, Arr and interface{}. Any other type must match exactly.
+func Get[T any](obj Obj, path string) (T, error) {
+	var zero T
+	p, err := ParsePath(path)
+	if err != nil {
+		return zero, err
+	}
+	return GetPath[T](obj, p)
+}
+
+// GetPath is like #Get() but accepts an already parsed path.
+func GetPath[T any](obj Obj, path Path) (T, error) {
+	var zero T
+	if len(path) == 0 {
+		return convert[T](obj)
+	}
+
+	parent, err := Lookup(obj, path[:len(path)-1])
+	if err != nil {
+		return zero, err
+	}
+
+	last := path[len(path)-1]
+	v, err := child(parent, last)
+	if err != nil {
+		return zero, withPath(path[:len(path)-1], err)
+	}
+
+	// arrays are resolved through the accessor of the parent, so that a raw slice is replaced by a pointer
+	// and appending to the result is visible in the document
+	if isArrType[T]() {
+		if arr, err := asArrayOf(parent, last); err == nil {
+			return any(arr).(T), nil
+		}
+	}
+
+	res, err := convert[T](v)
+	if err != nil {
+		return zero, withPath(path, err)
+	}
+	return res, nil
+}
+
+// Opt is like #Get() but returns the fallback on any error.
+func Opt[T any](obj Obj, path string, fallback T) T {
+	v, err := Get[T](obj, path)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+// Set resolves the path (see #ParsePath()) beginning at the given object and replaces the value. Missing or null
+// intermediate values are created as Obj or Arr, depending on the type of the next segment. Indices must
+// always be within the bounds of an array. Times, durations and byte slices are stored in the same
+// representation as the typed Put methods use.
+func Set[T any](obj Obj, path string, value T) error {
+	p, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	return SetPath(obj, p, value)
+}
+
+// SetPath is like #Set() but accepts an already parsed path. Persistent containers cannot be modified in
+// place and return an error, use #PersistentObj.SetIn() instead.
+func SetPath[T any](obj Obj, path Path, value T) error {
+	if len(path) == 0 {
+		return fmt.Errorf("cannot replace the root object")
+	}
+
+	var cur interface{} = obj
+	for i, seg := range path[:len(path)-1] {
+		if isPersistent(cur) {
+			return withPath(path[:i], immutable(cur))
+		}
+		next, err := childContainer(cur, seg, path[i+1])
+		if err != nil {
+			return withPath(path[:i], err)
+		}
+		cur = next
+	}
+
+	if isPersistent(cur) {
+		return withPath(path[:len(path)-1], immutable(cur))
+	}
+	last := path[len(path)-1]
+	v := storable(value)
+	switch t := cur.(type) {
+	case Obj:
+		key, ok := last.(string)
+		if !ok {
+			return withPath(path[:len(path)-1], typeMismatch(cur, "array"))
+		}
+		t.Put(key, v)
+	case Arr:
+		idx, ok := last.(int)
+		if !ok {
+			return withPath(path[:len(path)-1], typeMismatch(cur, "object"))
+		}
+		if idx < 0 || idx >= t.Size() {
+			return withPath(path[:len(path)-1], outOfBoundsAt(idx, t.Size()))
+		}
+		t.Put(idx, v)
+	}
+	return nil
+}
+
+// childContainer returns the Obj or Arr for the segment and creates it, if required
+func childContainer(cur interface{}, seg interface{}, next interface{}) (interface{}, error) {
+	_, wantObj := next.(string)
+
+	switch t := cur.(type) {
+	case Obj:
+		key, ok := seg.(string)
+		if !ok {
+			return nil, typeMismatch(cur, "array")
+		}
+		if t.IsNull(key) {
+			if wantObj {
+				res := NewObj()
+				t.PutObject(key, res)
+				return res, nil
+			}
+			res := NewArr()
+			t.PutArray(key, res)
+			return res, nil
+		}
+		if wantObj {
+			if obj, ok := toObj(t.Get(key)); ok {
+				return obj, nil
+			}
+			return nil, withPath(Path{key}, typeMismatch(t.Get(key), "object"))
+		}
+		return t.AsArray(key)
+	case Arr:
+		idx, ok := seg.(int)
+		if !ok {
+			return nil, typeMismatch(cur, "object")
+		}
+		if idx < 0 || idx >= t.Size() {
+			return nil, outOfBoundsAt(idx, t.Size())
+		}
+		if t.IsNull(idx) {
+			if wantObj {
+				res := NewObj()
+				t.PutObject(idx, res)
+				return res, nil
+			}
+			res := NewArr()
+			t.PutArray(idx, res)
+			return res, nil
+		}
+		if wantObj {
+			return t.AsObject(idx)
+		}
+		return t.AsArray(idx)
+	}
+	return nil, typeMismatch(cur, "object")
+}
+
+// asArrayOf invokes the AsArray accessor of the given container
+func asArrayOf(container interface{}, seg interface{}) (Arr, error) {
+	switch s := seg.(type) {
+	case string:
+		if obj, ok := toObj(container); ok {
+			return obj.AsArray(s)
+		}
+	case int:
+		if arr, ok := toArr(container); ok {
+			return arr.AsArray(s)
+		}
+	}
+	return nil, typeMismatch(container, "array")
+}
+
+// isArrType checks if T is the Arr interface itself
+func isArrType[T any]() bool {
+	_, ok := any((*T)(nil)).(*Arr)
+	return ok
+}
+
+// storable converts the value into the representation which the typed Put methods use
+func storable(v interface{}) interface{} {
+	switch t := v.(type) {
+	case time.Time:
+		return formatTime(t)
+	case time.Duration:
+		return formatDuration(t)
+	case []byte:
+		return copyBytes(t)
+	case int:
+		return int64(t)
+	}
+	return v
+}
+
+// convert applies the conversion heuristics of the typed accessors
+func convert[T any](v interface{}) (T, error) {
+	var res T
+	var err error
+	switch p := any(&res).(type) {
+	case *interface{}:
+		*p = v
+	case *int64:
+		*p, err = asInt64(v)
+	case *int:
+		var i int64
+		i, err = asInt64(v)
+		*p = int(i)
+	case *float64:
+		*p, err = asFloat64(v)
+	case *bool:
+		*p, err = asBool(v)
+	case *string:
+		*p, err = asString(v)
+	case *time.Time:
+		*p, err = asTime(v)
+	case *time.Duration:
+		*p, err = asDuration(v)
+	case *[]byte:
+		*p, err = asBytes(v)
+	case *Obj:
+		obj, ok := toObj(v)
+		if !ok {
+			return res, typeMismatch(v, "object")
+		}
+		*p = obj
+	case *Arr:
+		arr, ok := toArr(v)
+		if !ok {
+			return res, typeMismatch(v, "array")
+		}
+		*p = arr
+	default:
+		t, ok := v.(T)
+		if !ok {
+			return res, typeMismatch(v, fmt.Sprintf("%T", res))
+		}
+		res = t
+	}
+	return res, err
+}
+
+// All returns an iterator over all keys and values. The order is undefined.
+func (o Object) All() iter.Seq2[string, interface{}] {
+	return ObjAll(o)
+}
+
+// Objects returns an iterator over all values which are objects.
+func (o Object) Objects() iter.Seq2[string, Obj] {
+	return ObjObjects(o)
+}
+
+// Strings returns an iterator over all values which can be converted into a string, which excludes
+// objects and arrays.
+func (o Object) Strings() iter.Seq2[string, string] {
+	return ObjStrings(o)
+}
+
+// All returns an iterator over all indices and values in order.
+func (a *Array) All() iter.Seq2[int, interface{}] {
+	return ArrAll(a)
+}
+
+// Objects returns an iterator over all elements which are objects.
+func (a *Array) Objects() iter.Seq2[int, Obj] {
+	return ArrObjects(a)
+}
+
+// Strings returns an iterator over all elements which can be converted into a string, which excludes
+// objects and arrays.
+func (a *Array) Strings() iter.Seq2[int, string] {
+	return ArrStrings(a)
+}
+
+// ObjAll returns an iterator over all keys and values of any Obj implementation, like a parsed document or a
+// SyncObj. It iterates a snapshot of the keys, so the order is undefined.
+func ObjAll(obj Obj) iter.Seq2[string, interface{}] {
+	return func(yield func(string, interface{}) bool) {
+		keys := obj.Keys()
+		for i := 0; i < keys.Size(); i++ {
+			k := keys.Get(i)
+			if !yield(k, obj.Get(k)) {
+				return
+			}
+		}
+	}
+}
+
+// ObjObjects returns an iterator over all values of the object which are objects.
+func ObjObjects(obj Obj) iter.Seq2[string, Obj] {
+	return func(yield func(string, Obj) bool) {
+		for k, v := range ObjAll(obj) {
+			if o, ok := toObj(v); ok {
+				if !yield(k, o) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ObjStrings returns an iterator over all values of the object which can be converted into a string,
+// which excludes objects and arrays.
+func ObjStrings(obj Obj) iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for k, v := range ObjAll(obj) {
+			if isContainer(v) {
+				continue
+			}
+			if s, err := asString(v); err == nil {
+				if !yield(k, s) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ArrAll returns an iterator over all indices and values of any Arr implementation in order. The size is
+// read on each step.
+func ArrAll(arr Arr) iter.Seq2[int, interface{}] {
+	return func(yield func(int, interface{}) bool) {
+		for i := 0; i < arr.Size(); i++ {
+			if !yield(i, arr.Get(i)) {
+				return
+			}
+		}
+	}
+}
+
+// ArrObjects returns an iterator over all elements of the array which are objects.
+func ArrObjects(arr Arr) iter.Seq2[int, Obj] {
+	return func(yield func(int, Obj) bool) {
+		for i, v := range ArrAll(arr) {
+			if o, ok := toObj(v); ok {
+				if !yield(i, o) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ArrStrings returns an iterator over all elements of the array which can be converted into a string, which
+// excludes objects and arrays.
+func ArrStrings(arr Arr) iter.Seq2[int, string] {
+	return func(yield func(int, string) bool) {
+		for i, v := range ArrAll(arr) {
+			if isContainer(v) {
+				continue
+			}
+			if s, err := asString(v); err == nil {
+				if !yield(i, s) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// isContainer returns true for wrapped or raw objects and arrays
+func isContainer(v interface{}) bool {
+	if _, ok := toObj(v); ok {
+		return true
+	}
+	_, ok := toArr(v)
+	return ok
+}
@@ -0,0 +1,123 @@
+package xobj
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	obj, err := Parse([]byte(`{"a":{"b":[{"c":"42"},{"d":"2019-03-04"}]},"list":[1,2]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := Get[int64](obj, "a.b[0].c"); err != nil || v != 42 {
+		t.Fatal("unexpected", v, err)
+	}
+
+	if v, err := Get[time.Time](obj, "a.b[1].d"); err != nil || v.Day() != 4 {
+		t.Fatal("unexpected", v, err)
+	}
+
+	if _, err := Get[bool](obj, "a.b[0].c"); !errors.Is(err, ErrConversion) {
+		t.Fatal("unexpected", err)
+	}
+
+	if v := Opt[string](obj, "a.b[5].c", "none"); v != "none" {
+		t.Fatal("unexpected", v)
+	}
+
+	arr, err := Get[Arr](obj, "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	arr.AddInt64(3)
+	if v := Opt[int](obj, "list[2]", 0); v != 3 {
+		t.Fatal("append not visible", obj.String())
+	}
+}
+
+func TestSet(t *testing.T) {
+	obj := NewObj()
+	if err := Set(obj, "a.b.c", 3.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(obj, "a.d", 90*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if obj.String() != `{"a":{"b":{"c":3.5},"d":"1m30s"}}` {
+		t.Fatal("unexpected", obj.String())
+	}
+
+	if err := Set(obj, "a.list[0]", "x"); !errors.Is(err, ErrOutOfBounds) {
+		t.Fatal("unexpected", err)
+	}
+	if err := Set(obj, "a.b.c.x", "y"); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatal("unexpected", err)
+	}
+}
+
+func TestObject_All(t *testing.T) {
+	obj, err := Parse([]byte(json0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for k := range ObjAll(obj) {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "hello" || keys[1] != "list" {
+		t.Fatal("unexpected", keys)
+	}
+
+	for k, v := range ObjStrings(obj) {
+		if k != "hello" || v != "world" {
+			t.Fatal("unexpected", k, v)
+		}
+	}
+
+	arr := obj.OptArray("list")
+	var strs []string
+	for _, s := range ArrStrings(arr) {
+		strs = append(strs, s)
+	}
+	if len(strs) != 6 {
+		t.Fatal("unexpected", strs)
+	}
+
+	for i, o := range ArrObjects(arr) {
+		if i != 6 || o.OptString("k", "") != "v" {
+			t.Fatal("unexpected", i, o)
+		}
+	}
+}
+
+func TestAll_SyncObj(t *testing.T) {
+	obj := NewSyncObj(NewObj().PutString("a", "x").PutObject("b", NewObj().PutString("c", "y")))
+	n := 0
+	for k, v := range ObjStrings(obj) {
+		if k != "a" || v != "x" {
+			t.Fatal("unexpected", k, v)
+		}
+		n++
+	}
+	for k, o := range ObjObjects(obj) {
+		if k != "b" || o.OptString("c", "") != "y" {
+			t.Fatal("unexpected", k, o)
+		}
+		n++
+	}
+	arr := NewSyncObj(NewObj().PutArray("list", NewArr().AddString("u").AddString("v"))).OptArray("list")
+	for i, v := range ArrAll(arr) {
+		if v != []string{"u", "v"}[i] {
+			t.Fatal("unexpected", i, v)
+		}
+		n++
+	}
+	if n != 4 {
+		t.Fatal("unexpected", n)
+	}
+}
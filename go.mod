@@ -1,5 +1,5 @@
 module github.com/worldiety/xobj
 
-go 1.12
+go 1.23
 
 require github.com/worldiety/jsonml v0.0.3
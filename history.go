@@ -0,0 +1,234 @@
+package xobj
+
+// historyEntry is a group of changes, which is undone and redone at once
+type historyEntry struct {
+	name    string
+	changes []*ChangeEvent
+}
+
+// A History records all modifications of an ObservableObj, so that they can be undone and redone. Each
+// notification of the ObservableObj becomes an entry, so that all changes of a transaction are undone
+// together. It is not thread safe, like the ObservableObj itself.
+type History struct {
+	obj      *ObservableObj
+	sub      *Subscription
+	undo     []*historyEntry
+	redo     []*historyEntry
+	maxSize  int
+	depth    int
+	name     string
+	replayed map[*ChangeEvent]bool
+}
+
+// NewHistory starts recording the changes of the given object, which is wrapped into an ObservableObj,
+// unless it is already one. At most maxSize entries are kept, zero means unlimited. Edits must be made
+// through #History.Obj().
+func NewHistory(obj Obj, maxSize int) *History {
+	o, ok := obj.(*ObservableObj)
+	if !ok {
+		o = NewObservableObj(obj)
+	}
+	h := &History{obj: o, maxSize: maxSize, replayed: map[*ChangeEvent]bool{}}
+	// the subscription of the root path cannot fail
+	h.sub, _ = o.Subscribe("", ChangeListenerFunc(h.record))
+	return h
+}
+
+// Obj returns the object, whose modifications are recorded.
+func (h *History) Obj() *ObservableObj {
+	return h.obj
+}
+
+// Close stops recording.
+func (h *History) Close() {
+	h.sub.Cancel()
+	h.replayed = map[*ChangeEvent]bool{}
+}
+
+func (h *History) record(events ChangeList) {
+	entry := &historyEntry{name: h.name}
+	for i := 0; i < events.Size(); i++ {
+		e := events.Get(i)
+		if h.replayed[e] {
+			delete(h.replayed, e)
+			continue
+		}
+		// listeners share the events, so keep private copies
+		entry.changes = append(entry.changes, &ChangeEvent{Path: e.Path, Op: e.Op, Old: syncValue(e.Old), New: syncValue(e.New)})
+	}
+	if len(entry.changes) == 0 {
+		return
+	}
+	h.undo = append(h.undo, entry)
+	h.redo = nil
+	if h.maxSize > 0 && len(h.undo) > h.maxSize {
+		h.undo = append([]*historyEntry(nil), h.undo[len(h.undo)-h.maxSize:]...)
+	}
+}
+
+// Begin starts a named transaction, whose changes become a single entry when the matching #Commit() is
+// invoked. Transactions can be nested, the name of the outermost one is used.
+func (h *History) Begin(name string) {
+	if h.depth == 0 {
+		h.name = name
+	}
+	h.depth++
+	h.obj.Begin()
+}
+
+// Commit ends a transaction started by #Begin().
+func (h *History) Commit() {
+	if h.depth == 0 {
+		panic("xobj: commit without begin")
+	}
+	h.depth--
+	h.obj.Commit()
+	if h.depth == 0 {
+		h.name = ""
+	}
+}
+
+// Size returns the amount of entries which can be undone.
+func (h *History) Size() int {
+	return len(h.undo)
+}
+
+// CanUndo returns true, if there is an entry to undo.
+func (h *History) CanUndo() bool {
+	return len(h.undo) > 0
+}
+
+// CanRedo returns true, if there is an undone entry, which has not been discarded by a new modification.
+func (h *History) CanRedo() bool {
+	return len(h.redo) > 0
+}
+
+// UndoName returns the name of the transaction which is undone next, which is empty for unnamed changes.
+func (h *History) UndoName() string {
+	if len(h.undo) == 0 {
+		return ""
+	}
+	return h.undo[len(h.undo)-1].name
+}
+
+// RedoName returns the name of the transaction which is redone next.
+func (h *History) RedoName() string {
+	if len(h.redo) == 0 {
+		return ""
+	}
+	return h.redo[len(h.redo)-1].name
+}
+
+// Undo reverts the last entry and returns false, if there was nothing to undo. Listeners of the object are
+// notified about the reverting changes. If a change cannot be reverted, the already reverted changes of the
+// entry are applied again.
+func (h *History) Undo() (bool, error) {
+	if len(h.undo) == 0 {
+		return false, nil
+	}
+	entry := h.undo[len(h.undo)-1]
+	err := h.apply(func() error {
+		for i := len(entry.changes) - 1; i >= 0; i-- {
+			if err := h.revert(entry.changes[i]); err != nil {
+				for _, c := range entry.changes[i+1:] {
+					h.replay(c)
+				}
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, entry)
+	return true, nil
+}
+
+// Redo applies the last undone entry again and returns false, if there was nothing to redo. If a change
+// cannot be applied, the already applied changes of the entry are reverted again.
+func (h *History) Redo() (bool, error) {
+	if len(h.redo) == 0 {
+		return false, nil
+	}
+	entry := h.redo[len(h.redo)-1]
+	err := h.apply(func() error {
+		for i, c := range entry.changes {
+			if err := h.replay(c); err != nil {
+				for j := i - 1; j >= 0; j-- {
+					h.revert(entry.changes[j])
+				}
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, entry)
+	return true, nil
+}
+
+// revert performs the inverse of the recorded change
+func (h *History) revert(c *ChangeEvent) error {
+	switch c.Op {
+	case ChangeAdd:
+		return h.obj.root.apply(ChangeRemove, c.Path, nil)
+	case ChangeRemove:
+		return h.obj.root.apply(ChangeAdd, c.Path, c.Old)
+	default:
+		return h.obj.root.apply(ChangeReplace, c.Path, c.Old)
+	}
+}
+
+// replay performs the recorded change again
+func (h *History) replay(c *ChangeEvent) error {
+	return h.obj.root.apply(c.Op, c.Path, c.New)
+}
+
+// apply replays changes within a transaction, without recording them. The events are marked instead of
+// suppressing the recording while replaying, because within an outer transaction or a listener they are
+// delivered later, possibly together with other changes. The marks are removed by #History.record() or
+// right after the commit, if the events have been delivered already.
+func (h *History) apply(fn func() error) error {
+	root := h.obj.root
+	h.obj.Begin()
+	start := len(root.pending)
+	err := fn()
+	events := append([]*ChangeEvent(nil), root.pending[start:]...)
+	for _, e := range events {
+		h.replayed[e] = true
+	}
+	h.obj.Commit()
+	if root.depth == 0 && !root.delivering {
+		for _, e := range events {
+			delete(h.replayed, e)
+		}
+	}
+	return err
+}
+
+// Clear discards all entries.
+func (h *History) Clear() {
+	h.undo = nil
+	h.redo = nil
+}
+
+// Patch returns all entries which can be undone as a JSON Patch (RFC 6902), from the oldest to the newest
+// change. Applying it to the state before the oldest entry results in the current state.
+func (h *History) Patch() Arr {
+	res := NewArr()
+	for _, entry := range h.undo {
+		for _, c := range entry.changes {
+			op := NewObj().PutString("op", c.Op.String()).PutString("path", c.Path.Pointer())
+			if c.Op != ChangeRemove {
+				op.Put("value", syncValue(c.New))
+			}
+			res.AddObject(op)
+		}
+	}
+	return res
+}
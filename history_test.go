@@ -0,0 +1,124 @@
+package xobj
+
+import (
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(NewObj().PutString("name", "a"), 0)
+	obj := h.Obj()
+
+	obj.PutString("name", "b")
+	h.Begin("add tags")
+	tags := obj.OptArray("tags")
+	tags.AddString("x").AddString("y")
+	h.Commit()
+	tags.Remove(0)
+
+	if h.Size() != 3 || h.UndoName() != "" {
+		t.Fatal("unexpected", h.Size(), h.UndoName())
+	}
+
+	states := []string{
+		`{"name":"b","tags":["x","y"]}`,
+		`{"name":"b"}`,
+		`{"name":"a"}`,
+	}
+	for i, expected := range states {
+		if ok, err := h.Undo(); !ok || err != nil {
+			t.Fatal("unexpected", ok, err)
+		}
+		if obj.String() != expected {
+			t.Fatal("unexpected", i, obj.String())
+		}
+		if i == 0 && h.UndoName() != "add tags" {
+			t.Fatal("unexpected", h.UndoName())
+		}
+	}
+	if ok, _ := h.Undo(); ok || !h.CanRedo() {
+		t.Fatal("unexpected")
+	}
+
+	for i := 0; i < 3; i++ {
+		if ok, err := h.Redo(); !ok || err != nil {
+			t.Fatal("unexpected", ok, err)
+		}
+	}
+	if obj.String() != `{"name":"b","tags":["y"]}` {
+		t.Fatal("unexpected", obj.String())
+	}
+
+	expected := `[{"op":"replace","path":"/name","value":"b"},{"op":"add","path":"/tags","value":[]},` +
+		`{"op":"add","path":"/tags/0","value":"x"},{"op":"add","path":"/tags/1","value":"y"},{"op":"remove","path":"/tags/0"}]`
+	if s := h.Patch().String(); s != expected {
+		t.Fatal("unexpected", s)
+	}
+
+	// a new change discards the redo entries
+	h.Undo()
+	obj.PutString("name", "c")
+	if h.CanRedo() {
+		t.Fatal("unexpected")
+	}
+}
+
+func TestHistory_UndoInTransaction(t *testing.T) {
+	h := NewHistory(NewObj().PutString("name", "a"), 0)
+	obj := h.Obj()
+	obj.PutString("name", "b")
+
+	h.Begin("outer")
+	if ok, err := h.Undo(); !ok || err != nil {
+		t.Fatal("unexpected", ok, err)
+	}
+	h.Commit()
+	if h.Size() != 0 || !h.CanRedo() || obj.String() != `{"name":"a"}` {
+		t.Fatal("unexpected", h.Size(), obj.String())
+	}
+
+	// only the other changes of the transaction are recorded
+	obj.Batch(func() {
+		if ok, err := h.Redo(); !ok || err != nil {
+			t.Fatal("unexpected", ok, err)
+		}
+		obj.PutString("other", "c")
+	})
+	if h.Size() != 2 || obj.String() != `{"name":"b","other":"c"}` {
+		t.Fatal("unexpected", h.Size(), obj.String())
+	}
+	if ok, _ := h.Undo(); !ok || obj.String() != `{"name":"b"}` {
+		t.Fatal("unexpected", obj.String())
+	}
+}
+
+func TestHistory_UndoFails(t *testing.T) {
+	h := NewHistory(NewObj(), 0)
+	obj := h.Obj()
+	obj.OptArray("l").AddInt64(1)
+	h.Begin("")
+	obj.OptArray("l").AddInt64(2)
+	obj.PutInt64("v", 1)
+	h.Commit()
+
+	// the element is gone, so the entry cannot be undone completely
+	h.Close()
+	obj.OptArray("l").Remove(1)
+	if ok, err := h.Undo(); ok || err == nil {
+		t.Fatal("unexpected", ok, err)
+	}
+	if h.Size() != 3 || obj.String() != `{"l":[1],"v":1}` || len(h.replayed) != 0 {
+		t.Fatal("unexpected", h.Size(), obj.String(), len(h.replayed))
+	}
+}
+
+func TestHistory_MaxSize(t *testing.T) {
+	h := NewHistory(nil, 2)
+	for i := 0; i < 5; i++ {
+		h.Obj().PutInt64("v", int64(i))
+	}
+	h.Undo()
+	h.Undo()
+	if ok, _ := h.Undo(); ok || h.Obj().OptInt64("v", -1) != 2 {
+		t.Fatal("unexpected", h.Obj())
+	}
+}
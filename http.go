@@ -1,14 +1,14 @@
 package xobj
 
 import (
-	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,39 +16,54 @@ import (
 // You should not expect that to be capable of everything, and you are probably
 // better of using the standard client or resty in production code.
 type RequestBuilder struct {
-	http                  bool
+	client                *Client
+	scheme                string
 	host                  string
 	port                  int
 	path                  string
+	rawPath               string
+	pathParams            map[string]string
+	base                  *url.URL
+	err                   error
 	retry                 int
+	retryPolicy           RetryPolicy
+	attempts              []*Attempt
+	timing                *timingTrace
 	header                http.Header
 	query                 url.Values
 	responseHeaderTimeout time.Duration
 	dialTimeout           time.Duration
+	tlsHandshakeTimeout   time.Duration
+	timeout               time.Duration
+	ctx                   context.Context
 	method                string
 	body                  io.Reader
+	openBody              func() (io.ReadCloser, error)
+	contentLength         int64
+	mutex                 sync.Mutex
 	pendingCancelFunc     func()
+	cancelled             bool
 }
 
-// NewRequest returns a RequestBuilder with some useful defaults.
+// NewRequest returns a RequestBuilder of the shared #DefaultClient() with some useful defaults.
 // You have to create a new request for each query to want to make. Do not recycle it.
 func NewRequest() *RequestBuilder {
-	return &RequestBuilder{retry: 3, header: http.Header{}, query: url.Values{}, responseHeaderTimeout: time.Second * 30, dialTimeout: time.Second * 30}
+	return DefaultClient().NewRequest()
 }
 
 // Http sets the connection to http
 func (r *RequestBuilder) Http() *RequestBuilder {
-	r.http = true
+	r.scheme = "http"
 	return r
 }
 
-// Https sets the connection to use https only, which is the default
+// Https sets the connection to use https only, which is the default, unless a base url defines the scheme
 func (r *RequestBuilder) Https() *RequestBuilder {
-	r.http = false
+	r.scheme = "https"
 	return r
 }
 
-// Host sets the host part
+// Host sets the host part, which may also be an IPv6 address with or without brackets
 func (r *RequestBuilder) Host(host string) *RequestBuilder {
 	r.host = host
 	return r
@@ -61,17 +76,31 @@ func (r *RequestBuilder) Port(port int) *RequestBuilder {
 }
 
 // Path concates the given path segments with /. But can also be just a single string already with slashes.
+// The segments are escaped and may contain placeholders like {id}, see #RequestBuilder.PathParam(). If a base
+// url is set, the path is appended to its path.
 func (r *RequestBuilder) Path(p ...string) *RequestBuilder {
 	r.path = strings.Join(p, "/")
+	r.rawPath = ""
 	return r
 }
 
-// Retry sets the amount of retries before a failure is actually returned. The default is 3.
+// Retry sets the maximum amount of attempts of the default RetryPolicy, see #DefaultRetryPolicy(). The default is 3.
 func (r *RequestBuilder) Retry(retry int) *RequestBuilder {
 	r.retry = retry
 	return r
 }
 
+// RetryPolicy replaces the default policy, which decides whether a failed attempt is repeated.
+func (r *RequestBuilder) RetryPolicy(policy RetryPolicy) *RequestBuilder {
+	r.retryPolicy = policy
+	return r
+}
+
+// Attempts returns the attempts of the last request.
+func (r *RequestBuilder) Attempts() []*Attempt {
+	return r.attempts
+}
+
 // Header adds a key/value into the header part of the request
 func (r *RequestBuilder) Header(key, value string) *RequestBuilder {
 	r.header.Add(key, value)
@@ -80,41 +109,88 @@ func (r *RequestBuilder) Header(key, value string) *RequestBuilder {
 
 // Query adds a key/value combination into the query part of the url
 func (r *RequestBuilder) Query(key, value string) *RequestBuilder {
-	r.header.Add(key, value)
+	r.query.Add(key, value)
 	return r
 }
 
-// DialTimeout influences the timeout to establish a connection. The default is 30 seconds.
+// DialTimeout influences the timeout to resolve the name and establish a tcp connection. The default is 30 seconds.
 func (r *RequestBuilder) DialTimeout(duration time.Duration) *RequestBuilder {
 	r.dialTimeout = duration
 	return r
 }
 
-// ResponseHeaderTimeout influences the timeout before the first byte has been received. The default is 30 seconds.
+// TLSHandshakeTimeout limits the tls handshake of a new connection. If 0, only the timeout of the client applies.
+func (r *RequestBuilder) TLSHandshakeTimeout(duration time.Duration) *RequestBuilder {
+	r.tlsHandshakeTimeout = duration
+	return r
+}
+
+// ResponseHeaderTimeout influences the timeout before the first byte has been received, after the request has
+// been written. The default is 30 seconds.
 func (r *RequestBuilder) ResponseHeaderTimeout(duration time.Duration) *RequestBuilder {
 	r.responseHeaderTimeout = duration
 	return r
 }
 
-// Body sets a reader to be consumed as a body for the request
+// Timeout limits the entire request, including all attempts, the backoff between them and reading the
+// response. The default is 0, which means no limit.
+func (r *RequestBuilder) Timeout(duration time.Duration) *RequestBuilder {
+	r.timeout = duration
+	return r
+}
+
+// WithContext uses the given context for the request, so that its cancellation and deadline abort all attempts
+// and the backoff between them.
+func (r *RequestBuilder) WithContext(ctx context.Context) *RequestBuilder {
+	r.ctx = ctx
+	return r
+}
+
+// Body sets a reader to be consumed as a body for the request. Only a *bytes.Reader, *bytes.Buffer or
+// *strings.Reader can be sent again by retries, see #RequestBuilder.BufferedBody() and #RequestBuilder.BodyFunc().
 func (r *RequestBuilder) Body(reader io.Reader) *RequestBuilder {
 	r.body = reader
+	r.openBody = nil
 	return r
 }
 
 // JSONBody converts the given obj into a json and sets the content type accordingly
 func (r *RequestBuilder) JSONBody(obj Obj) *RequestBuilder {
-	r.header.Set("Content-Type", "application/json")
-	r.body = bytes.NewReader([]byte(obj.String()))
-	return r
+	return r.EncodedBody("json", obj)
 }
 
-// Cancel has only an effect while no timeout has run out, the request has ever been started, using an http verb
-// and the request has never been cancelled before and the request is still pending.
+// Cancel aborts the pending request, including any further attempts. A request which is started afterwards
+// fails immediately.
 func (r *RequestBuilder) Cancel() {
-	cfunc := r.pendingCancelFunc
-	if cfunc != nil {
-		cfunc()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cancelled = true
+	if r.pendingCancelFunc != nil {
+		r.pendingCancelFunc()
+	}
+}
+
+// begin returns the context of the entire request
+func (r *RequestBuilder) begin() (context.Context, func()) {
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cancelTimeout := func() {}
+	if r.timeout > 0 {
+		ctx, cancelTimeout = context.WithTimeoutCause(ctx, r.timeout, &TimeoutError{Phase: "total", Duration: r.timeout})
+	}
+	ctx, cancel := context.WithCancel(ctx)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.cancelled {
+		cancel()
+	}
+	r.pendingCancelFunc = cancel
+	return ctx, func() {
+		cancel()
+		cancelTimeout()
 	}
 }
 
@@ -127,19 +203,35 @@ func (r *RequestBuilder) Get() (Obj, int, error) {
 // GetBytes performs a get request based on the current configuration and loads the entire response body into a slice.
 // Otherwise returns an error. Tries to always return the http status code.
 func (r *RequestBuilder) GetBytes() ([]byte, int, error) {
-	r.method = "GET"
-	var resData []byte
-	var statusCode int
-	err := r.doRequest(func(req *http.Request, res *http.Response) error {
-		statusCode = res.StatusCode
-		data, err := ioutil.ReadAll(res.Body)
-		if err != nil {
-			return err
-		}
-		resData = data
+	res, err := r.Send("GET")
+	if err != nil {
+		return nil, statusCode(res), err
+	}
+	return res.Body, res.StatusCode, nil
+}
+
+// Send performs a request based on the current configuration and reads the entire response. An error is only
+// returned, if no response has been received or its body could not be read, use #Response.EnsureSuccess() to
+// check the status code. If reading the body fails, the response with the partial body is returned as well.
+func (r *RequestBuilder) Send(method string) (*Response, error) {
+	r.method = method
+	var res *Response
+	err := r.doRequest(func(req *http.Request, httpRes *http.Response) error {
+		data, err := io.ReadAll(httpRes.Body)
+		res = newResponse(httpRes, data)
+		res.Attempts = r.attempts
+		res.Timing = r.timing.done()
 		return err
 	})
-	return resData, statusCode, err
+	return res, err
+}
+
+// statusCode returns the status of the response or 0, if there is none
+func statusCode(res *Response) int {
+	if res == nil {
+		return 0
+	}
+	return res.StatusCode
 }
 
 // Do performs a request based on the current configuration and invokes the callback.
@@ -170,58 +262,25 @@ func (r *RequestBuilder) Post() (Obj, int, error) {
 
 // genericObjRequest always tries to parse the result as obj
 func (r *RequestBuilder) genericObjRequest(method string) (Obj, int, error) {
-	r.method = method
-	var obj Obj
-	var statusCode int
-	err := r.doRequest(func(req *http.Request, res *http.Response) error {
-		statusCode = res.StatusCode
-		data, err := ioutil.ReadAll(res.Body)
-		if err != nil {
-			return err
-		}
-		obj, err = Parse(data)
-		return err
-	})
-	return obj, statusCode, err
+	res, err := r.Send(method)
+	if err != nil {
+		return nil, statusCode(res), err
+	}
+	obj, err := res.Obj()
+	return obj, res.StatusCode, err
 }
 
 // doRequest performs the actual request based on builder settings and calls the onResult function which
 // in turn may return an error which is just delegated.
 func (r *RequestBuilder) doRequest(onResult func(req *http.Request, res *http.Response) error) error {
-	var transport http.RoundTripper = &http.Transport{
-		Proxy:                 http.ProxyFromEnvironment,
-		ResponseHeaderTimeout: r.responseHeaderTimeout,
-		TLSHandshakeTimeout:   r.dialTimeout,
-	}
-
-	client := &http.Client{Transport: transport}
+	client := r.client.client
 
-	sb := &strings.Builder{}
-	if r.http {
-		sb.WriteString("http://")
-		if r.port == 0 {
-			r.port = 80
-		}
-	} else {
-		sb.WriteString("https://")
-		if r.port == 0 {
-			r.port = 443
-		}
-	}
-
-	sb.WriteString(r.host)
-	sb.WriteString(":")
-	sb.WriteString(strconv.Itoa(r.port))
-	if !strings.HasPrefix(r.path, "/") {
-		sb.WriteString("/")
-	}
-	sb.WriteString(r.path)
-	if len(r.query) > 0 {
-		sb.WriteString("?")
-		sb.WriteString(r.query.Encode())
+	u, err := r.BuildURL()
+	if err != nil {
+		return err
 	}
 
-	req, err := http.NewRequest(r.method, sb.String(), r.body)
+	req, err := r.newRequest(u)
 	if err != nil {
 		return err
 	}
@@ -232,35 +291,133 @@ func (r *RequestBuilder) doRequest(onResult func(req *http.Request, res *http.Re
 		}
 	}
 
-	backOffTime := time.Millisecond * 200
+	policy := r.retryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy(r.retry)
+	}
+
+	ctx, done := r.begin()
+	defer done()
+
+	r.attempts = nil
 	var res *http.Response
-	for i := 0; i < r.retry; i++ {
-		ctx, cancelFunc := context.WithTimeout(context.Background(), r.dialTimeout)
-		r.pendingCancelFunc = cancelFunc
-		defer cancelFunc()
-
-		req = req.WithContext(ctx)
-
-		res, err = client.Do(req)
-		if err != nil {
-			logger.Info(Fields{"msg": "http query failed", "backoff": i, "url": sb.String(), "err": err.Error()})
-			time.Sleep(backOffTime)
-			backOffTime *= 2
-			//backoff give up
-			if i == r.retry-1 {
+	for n := 1; ; n++ {
+		if n > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
 				return err
 			}
+			req.Body = body
+		}
+
+		attemptCtx, cancelAttempt, stopTimers := withPhaseTimeouts(ctx, r.dialTimeout, r.tlsHandshakeTimeout, r.responseHeaderTimeout)
+
+		r.timing = &timingTrace{}
+		attemptCtx = r.timing.with(attemptCtx)
+		start := time.Now()
+		res, err = client.Do(req.WithContext(attemptCtx))
+		stopTimers()
+		err = requestError(req, attemptCtx, err)
+		attempt := &Attempt{Number: n, Request: req, Response: res, Err: err, Duration: time.Since(start)}
+		r.attempts = append(r.attempts, attempt)
+
+		if ctx.Err() != nil {
+			defer cancelAttempt(nil)
+			break
+		}
+		delay, retry := policy.Retry(attempt)
+		if !retry || !rewindable(req) {
+			// the body of the final response is read within the context of the attempt
+			defer cancelAttempt(nil)
+			break
+		}
 
+		if res != nil {
+			discard(res.Body)
+		}
+		cancelAttempt(nil)
+		attempt.Delay = delay
+		logger.Info(Fields{"msg": "http query failed", "attempt": n, "status": attempt.StatusCode(), "url": u.String(), "err": errString(err), "backoff": delay.String()})
+		if err := sleep(ctx, delay); err != nil {
+			return requestError(req, ctx, err)
 		}
 	}
 
-	err = onResult(req, res)
 	if err != nil {
 		return err
 	}
 
+	// the body is read within the total timeout as well
 	defer silentClose(res.Body)
-	return nil
+	return requestError(req, ctx, onResult(req, res))
+}
+
+// requestError replaces the cancellation of a timeout by the TimeoutError
+func requestError(req *http.Request, ctx context.Context, err error) error {
+	var timeout *TimeoutError
+	if err != nil && errors.As(context.Cause(ctx), &timeout) {
+		return fmt.Errorf("%s %s: %w", req.Method, req.URL, timeout)
+	}
+	return err
+}
+
+// sleep waits for the given duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newRequest creates the request with the body, which is opened for the first attempt
+func (r *RequestBuilder) newRequest(u *url.URL) (*http.Request, error) {
+	if r.openBody == nil {
+		return http.NewRequest(r.method, u.String(), r.body)
+	}
+
+	body, err := r.openBody()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(r.method, u.String(), body)
+	if err != nil {
+		silentClose(body)
+		return nil, err
+	}
+	req.GetBody = r.openBody
+	switch {
+	case r.contentLength == 0:
+		silentClose(body)
+		req.Body = http.NoBody
+		req.GetBody = func() (io.ReadCloser, error) {
+			return http.NoBody, nil
+		}
+	case r.contentLength > 0:
+		req.ContentLength = r.contentLength
+	}
+	return req, nil
+}
+
+// rewindable returns true, if the request has no body or the body can be created again
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// discard reads a bit of the remaining body, so that the connection can be reused, and closes it
+func discard(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, 64*1024))
+	silentClose(body)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
 }
 
 // silentClose just invokes the closer and logs any error
@@ -1,6 +1,13 @@
 package xobj
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -61,3 +68,158 @@ func TestRequestBuilder_Get4(t *testing.T) {
 	}
 
 }
+
+// startServer starts a local server, which is closed at the end of the test. The configure functions are
+// applied before the server is started.
+func startServer(t *testing.T, handler http.Handler, configure ...func(srv *http.Server)) *httptest.Server {
+	srv := httptest.NewUnstartedServer(handler)
+	for _, fn := range configure {
+		fn(srv.Config)
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testServer returns a builder for a local server, which answers with the given status codes in order
+func testServer(t *testing.T, codes ...int) (*RequestBuilder, *[]string) {
+	var bodies []string
+	srv := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(data))
+		code := codes[len(codes)-1]
+		if len(bodies) <= len(codes) {
+			code = codes[len(bodies)-1]
+		}
+		if code == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		w.WriteHeader(code)
+		_, _ = w.Write([]byte(`{"attempt":` + strconv.Itoa(len(bodies)) + `}`))
+	}))
+
+	policy := DefaultRetryPolicy(3)
+	policy.BaseDelay = time.Millisecond
+	return NewRequest().URL(srv.URL).RetryPolicy(policy), &bodies
+}
+
+func TestRequestBuilder_Retry(t *testing.T) {
+	r, bodies := testServer(t, 200)
+	obj, status, err := r.Get()
+	if err != nil || status != 200 || Opt[int64](obj, "attempt", 0) != 1 || len(*bodies) != 1 || len(r.Attempts()) != 1 {
+		t.Fatal("unexpected", obj, status, err, *bodies)
+	}
+
+	r, bodies = testServer(t, 503, 429, 200)
+	obj, status, err = r.Body(strings.NewReader("payload")).Put()
+	if err != nil || status != 200 || Opt[int64](obj, "attempt", 0) != 3 {
+		t.Fatal("unexpected", obj, status, err)
+	}
+	if strings.Join(*bodies, ",") != "payload,payload,payload" {
+		t.Fatal("unexpected", *bodies)
+	}
+	if attempts := r.Attempts(); len(attempts) != 3 || attempts[0].StatusCode() != 503 || attempts[1].Delay != 0 {
+		t.Fatal("unexpected", attempts)
+	}
+
+	// give up with the last response
+	r, bodies = testServer(t, 500)
+	_, status, _ = r.Get()
+	if status != 500 || len(*bodies) != 3 {
+		t.Fatal("unexpected", status, *bodies)
+	}
+
+	// not idempotent, not rewindable and not retryable
+	for _, fn := range []func(r *RequestBuilder) (Obj, int, error){
+		func(r *RequestBuilder) (Obj, int, error) { return r.Post() },
+		func(r *RequestBuilder) (Obj, int, error) { return r.Body(io.MultiReader(strings.NewReader("x"))).Put() },
+	} {
+		r, bodies = testServer(t, 503)
+		if _, status, _ := fn(r); status != 503 || len(*bodies) != 1 {
+			t.Fatal("unexpected", status, *bodies)
+		}
+	}
+	r, bodies = testServer(t, 404)
+	if _, status, _ := r.Get(); status != 404 || len(*bodies) != 1 {
+		t.Fatal("unexpected", status, *bodies)
+	}
+}
+
+func TestBackoffPolicy(t *testing.T) {
+	p := &BackoffPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	get, _ := http.NewRequest(http.MethodGet, "http://localhost", nil)
+	for n, expected := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		d, ok := p.Retry(&Attempt{Number: n + 1, Request: get, Err: io.ErrUnexpectedEOF})
+		if !ok || d != expected*time.Millisecond {
+			t.Fatal("unexpected", n, d)
+		}
+	}
+	if _, ok := p.Retry(&Attempt{Number: 10, Request: get, Err: io.ErrUnexpectedEOF}); ok {
+		t.Fatal("expected no retry")
+	}
+	if _, ok := p.Retry(&Attempt{Number: 1, Request: get, Err: context.Canceled}); ok {
+		t.Fatal("expected no retry")
+	}
+
+	// without MaxDelay, doubling must not overflow
+	unlimited := &BackoffPolicy{MaxAttempts: 1000, BaseDelay: time.Second}
+	if d, ok := unlimited.Retry(&Attempt{Number: 100, Request: get, Err: io.ErrUnexpectedEOF}); !ok || d != time.Hour {
+		t.Fatal("unexpected", d)
+	}
+
+	p.Jitter = 0.5
+	if d, _ := p.Retry(&Attempt{Number: 1, Request: get, Err: io.ErrUnexpectedEOF}); d < 50*time.Millisecond || d > 100*time.Millisecond {
+		t.Fatal("unexpected", d)
+	}
+
+	res := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"120"}}}
+	if d, ok := p.Retry(&Attempt{Number: 1, Request: get, Response: res}); !ok || d != time.Second {
+		t.Fatal("unexpected", d)
+	}
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	res.Header.Set("Retry-After", now.Add(5*time.Second).Format(http.TimeFormat))
+	if d, ok := RetryAfter(res, now); !ok || d != 5*time.Second {
+		t.Fatal("unexpected", d)
+	}
+}
+
+func TestRequestBuilder_WithContext(t *testing.T) {
+	slowPolicy := &BackoffPolicy{MaxAttempts: 5, BaseDelay: 10 * time.Second}
+
+	r, bodies := testServer(t, 503)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, _, err := r.WithContext(ctx).RetryPolicy(slowPolicy).Get()
+	if !errors.Is(err, context.Canceled) || time.Since(start) > 5*time.Second || len(*bodies) != 1 {
+		t.Fatal("unexpected", err, *bodies)
+	}
+
+	r, _ = testServer(t, 503)
+	_, _, err = r.Timeout(50 * time.Millisecond).RetryPolicy(slowPolicy).Get()
+	var timeout *TimeoutError
+	if !errors.As(err, &timeout) || timeout.Phase != "total" {
+		t.Fatal("unexpected", err)
+	}
+
+	// the total timeout includes reading the body
+	srv := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		select {
+		case <-req.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	_, status, err := NewRequest().URL(srv.URL).Timeout(50 * time.Millisecond).GetBytes()
+	if !errors.As(err, &timeout) || timeout.Phase != "total" || status != http.StatusOK || !strings.HasPrefix(err.Error(), "GET "+srv.URL) {
+		t.Fatal("unexpected", status, err)
+	}
+
+	r, bodies = testServer(t, 200)
+	r.Cancel()
+	if _, _, err = r.Get(); !errors.Is(err, context.Canceled) || len(*bodies) != 0 {
+		t.Fatal("unexpected", err, *bodies)
+	}
+}
@@ -0,0 +1,263 @@
+package xobj
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// A VarResolver provides the values of placeholders with a scheme, like ${env:HOME}. It returns false, if
+// the name is unknown.
+type VarResolver interface {
+	Resolve(name string) (interface{}, bool)
+}
+
+// VarResolverFunc allows to use an ordinary function as a VarResolver.
+type VarResolverFunc func(name string) (interface{}, bool)
+
+func (f VarResolverFunc) Resolve(name string) (interface{}, bool) {
+	return f(name)
+}
+
+// EnvResolver resolves environment variables and is registered for the env scheme by default.
+var EnvResolver VarResolver = VarResolverFunc(func(name string) (interface{}, bool) {
+	v, ok := os.LookupEnv(name)
+	return v, ok
+})
+
+// InterpolateOptions configure #Interpolate().
+type InterpolateOptions struct {
+	// Resolvers by scheme, like env for ${env:HOME}. EnvResolver is used for env, if not set.
+	Resolvers map[string]VarResolver
+
+	// KeepUnresolved leaves unknown placeholders without default as is, instead of failing.
+	KeepUnresolved bool
+}
+
+func (o InterpolateOptions) resolver(scheme string) (VarResolver, bool) {
+	if r, ok := o.Resolvers[scheme]; ok {
+		return r, true
+	}
+	if scheme == "env" {
+		return EnvResolver, true
+	}
+	return nil, false
+}
+
+// Interpolate returns a deep copy, in which the placeholders of all strings are expanded:
+//
+//	${db.host}        the value at the path (see #ParsePath()) within the same object, which is expanded as well
+//	${env:HOME}       the value of a VarResolver for the scheme env
+//	${port:-8080}     the fallback, if the value does not exist or is null. The fallback may contain placeholders.
+//	$${literal}       an escaped placeholder, which becomes ${literal}
+//
+// A string which consists of exactly one placeholder is replaced by the referenced value, so that numbers,
+// booleans and containers keep their type. Otherwise the values are embedded like #ToString() does.
+// References between values are followed and cycles are reported as errors. The object is not modified.
+func Interpolate(obj Obj, opts InterpolateOptions) (Obj, error) {
+	s := &interpolator{root: toObjOrEmpty(obj), opts: opts, done: map[string]interface{}{}, visiting: map[string]bool{}}
+	res, err := s.resolve(Path{})
+	if err != nil {
+		return nil, err
+	}
+	return res.(Object), nil
+}
+
+type interpolator struct {
+	root     Obj
+	opts     InterpolateOptions
+	done     map[string]interface{} // expanded values by path
+	visiting map[string]bool
+	stack    []string
+}
+
+// resolve returns the expanded value at the path
+func (s *interpolator) resolve(path Path) (interface{}, error) {
+	key := path.String()
+	if v, ok := s.done[key]; ok {
+		return v, nil
+	}
+	if s.visiting[key] {
+		start := len(s.stack) - 1
+		for s.stack[start] != key {
+			start--
+		}
+		return nil, fmt.Errorf("cyclic reference %s -> %s", strings.Join(s.stack[start:], " -> "), key)
+	}
+	s.visiting[key] = true
+	s.stack = append(s.stack, key)
+	defer func() {
+		delete(s.visiting, key)
+		s.stack = s.stack[:len(s.stack)-1]
+	}()
+
+	var raw interface{} = s.root
+	if len(path) > 0 {
+		v, err := Lookup(s.root, path)
+		if err != nil {
+			return nil, err
+		}
+		raw = v
+	}
+
+	res, err := s.value(path, raw)
+	if err != nil {
+		return nil, err
+	}
+	s.done[key] = res
+	return res, nil
+}
+
+func (s *interpolator) value(path Path, v interface{}) (interface{}, error) {
+	if obj, ok := toObj(v); ok && !isNil(v) {
+		res := Object{}
+		for _, k := range sortedKeys(obj) {
+			child, err := s.resolve(path.Child(k))
+			if err != nil {
+				return nil, err
+			}
+			res[k] = child
+		}
+		return res, nil
+	}
+
+	if arr, ok := toArr(v); ok && !isNil(v) {
+		res := make(Array, arr.Size())
+		for i := range res {
+			child, err := s.resolve(path.Child(i))
+			if err != nil {
+				return nil, err
+			}
+			res[i] = child
+		}
+		return &res, nil
+	}
+
+	if str, ok := v.(string); ok {
+		res, err := s.expand(str)
+		if err != nil {
+			return nil, withPath(path, err)
+		}
+		return res, nil
+	}
+	return cloneValue(v), nil
+}
+
+// expand replaces all placeholders of the string
+func (s *interpolator) expand(str string) (interface{}, error) {
+	if !strings.Contains(str, "$") {
+		return str, nil
+	}
+
+	// a single placeholder keeps the type of the value
+	if strings.HasPrefix(str, "${") {
+		if end, err := placeholderEnd(str, 2); err == nil && end == len(str)-1 {
+			return s.placeholder(str[2:end])
+		}
+	}
+
+	sb := &strings.Builder{}
+	for i := 0; i < len(str); {
+		switch {
+		case strings.HasPrefix(str[i:], "$${"):
+			end, err := placeholderEnd(str, i+3)
+			if err != nil {
+				return nil, err
+			}
+			sb.WriteString(str[i+1 : end+1])
+			i = end + 1
+		case strings.HasPrefix(str[i:], "${"):
+			end, err := placeholderEnd(str, i+2)
+			if err != nil {
+				return nil, err
+			}
+			v, err := s.placeholder(str[i+2 : end])
+			if err != nil {
+				return nil, err
+			}
+			sb.WriteString(ToString(v))
+			i = end + 1
+		default:
+			sb.WriteByte(str[i])
+			i++
+		}
+	}
+	return sb.String(), nil
+}
+
+// placeholderEnd returns the index of the closing brace, nested placeholders within defaults are skipped
+func placeholderEnd(str string, start int) (int, error) {
+	depth := 0
+	for i := start; i < len(str); i++ {
+		switch {
+		case strings.HasPrefix(str[i:], "${"):
+			depth++
+			i++
+		case str[i] == '}':
+			if depth == 0 {
+				return i, nil
+			}
+			depth--
+		}
+	}
+	return 0, fmt.Errorf("unterminated placeholder in '%s'", str)
+}
+
+// placeholder resolves the expression between the braces
+func (s *interpolator) placeholder(expr string) (interface{}, error) {
+	name, fallback, hasFallback := cutDefault(expr)
+
+	v, ok, err := s.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if ok && v != nil {
+		return v, nil
+	}
+	if hasFallback {
+		return s.expand(fallback)
+	}
+	if s.opts.KeepUnresolved {
+		return "${" + expr + "}", nil
+	}
+	return nil, fmt.Errorf("cannot resolve placeholder '%s'", name)
+}
+
+// cutDefault splits at the first :- which is not part of a nested placeholder
+func cutDefault(expr string) (string, string, bool) {
+	depth := 0
+	for i := 0; i < len(expr); i++ {
+		switch {
+		case strings.HasPrefix(expr[i:], "${"):
+			depth++
+			i++
+		case expr[i] == '}':
+			depth--
+		case depth == 0 && strings.HasPrefix(expr[i:], ":-"):
+			return expr[:i], expr[i+2:], true
+		}
+	}
+	return expr, "", false
+}
+
+func (s *interpolator) lookup(name string) (interface{}, bool, error) {
+	if scheme, rest, ok := strings.Cut(name, ":"); ok {
+		if r, ok := s.opts.resolver(scheme); ok {
+			v, ok := r.Resolve(rest)
+			return v, ok, nil
+		}
+	}
+
+	path, err := ParsePath(name)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid placeholder '%s': %w", name, err)
+	}
+	if _, err := Lookup(s.root, path); err != nil {
+		return nil, false, nil
+	}
+	v, err := s.resolve(path)
+	if err != nil {
+		return nil, false, err
+	}
+	return cloneValue(v), true, nil
+}
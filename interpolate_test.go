@@ -0,0 +1,71 @@
+package xobj
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("XOBJ_TEST_HOME", "/home/test")
+	obj := parseObj(t, `{
+		"db": {"host": "localhost", "port": 5432, "url": "postgres://${db.host}:${db.port}/${name}"},
+		"name": "${env:XOBJ_TEST_DB:-app}",
+		"port": "${db.port}",
+		"copy": "${db}",
+		"home": "${env:XOBJ_TEST_HOME}/data",
+		"fallback": "${missing:-${db.host}}",
+		"escaped": "$${db.host} costs $5",
+		"list": ["${db.host}", "${secret:token}"]
+	}`)
+
+	res, err := Interpolate(obj, InterpolateOptions{Resolvers: map[string]VarResolver{
+		"secret": VarResolverFunc(func(name string) (interface{}, bool) {
+			return "s3cr3t-" + name, true
+		}),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := parseObj(t, `{
+		"db": {"host": "localhost", "port": 5432, "url": "postgres://localhost:5432/app"},
+		"name": "app",
+		"port": 5432,
+		"copy": {"host": "localhost", "port": 5432, "url": "postgres://localhost:5432/app"},
+		"home": "/home/test/data",
+		"fallback": "localhost",
+		"escaped": "${db.host} costs $5",
+		"list": ["localhost", "s3cr3t-token"]
+	}`)
+	if !Equal(expected, res) {
+		t.Fatal("unexpected", res)
+	}
+	if Opt[string](obj, "port", "") != "${db.port}" {
+		t.Fatal("source has been modified")
+	}
+}
+
+func TestInterpolate_Errors(t *testing.T) {
+	_, err := Interpolate(parseObj(t, `{"a":"${b}","b":"x${c}","c":"${a}"}`), InterpolateOptions{})
+	if err == nil || !strings.Contains(err.Error(), "cyclic reference a -> b -> c -> a") {
+		t.Fatal("unexpected", err)
+	}
+
+	_, err = Interpolate(parseObj(t, `{"a":"${b}"}`), InterpolateOptions{})
+	if err == nil || !strings.Contains(err.Error(), "'b'") {
+		t.Fatal("unexpected", err)
+	}
+
+	_, err = Interpolate(parseObj(t, `{"a":"${b"}`), InterpolateOptions{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	res, err := Interpolate(parseObj(t, `{"a":"x ${b} ${c}","c":1}`), InterpolateOptions{KeepUnresolved: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Opt[string](res, "a", "") != "x ${b} 1" {
+		t.Fatal("unexpected", res)
+	}
+}
@@ -0,0 +1,392 @@
+package xobj
+
+import (
+	"sort"
+)
+
+type missing struct{}
+
+func (missing) String() string {
+	return "<missing>"
+}
+
+// Missing represents a value which does not exist in a version of a Conflict. A ConflictResolver
+// returns it to remove the value from the merged document.
+var Missing interface{} = missing{}
+
+func isMissing(v interface{}) bool {
+	_, ok := v.(missing)
+	return ok
+}
+
+// A Conflict is a value which has been changed differently in mine and theirs. Values which do not exist
+// in a version are Missing. If Range is true, the values are arrays which contain a range of conflicting
+// elements and the path denotes the index of the first element within the merged array.
+type Conflict struct {
+	Path   Path
+	Base   interface{}
+	Mine   interface{}
+	Theirs interface{}
+	Range  bool
+}
+
+// PathString returns the path in the notation of #ParsePath(), which is easier to use with gomobile.
+func (c *Conflict) PathString() string {
+	return c.Path.String()
+}
+
+func (c *Conflict) String() string {
+	return c.Path.String() + ": base=" + ToString(c.Base) + " mine=" + ToString(c.Mine) + " theirs=" + ToString(c.Theirs)
+}
+
+// A ConflictResolver decides conflicts of a #Merge(). It returns the merged value and true, or false to keep
+// the conflict. For ranges, the elements of a returned Arr are inserted and Missing inserts nothing.
+type ConflictResolver interface {
+	Resolve(c *Conflict) (interface{}, bool)
+}
+
+// ConflictResolverFunc allows to use an ordinary function as a ConflictResolver.
+type ConflictResolverFunc func(c *Conflict) (interface{}, bool)
+
+func (f ConflictResolverFunc) Resolve(c *Conflict) (interface{}, bool) {
+	return f(c)
+}
+
+// PreferMine resolves all conflicts using the local version.
+var PreferMine ConflictResolver = ConflictResolverFunc(func(c *Conflict) (interface{}, bool) {
+	return c.Mine, true
+})
+
+// PreferTheirs resolves all conflicts using the remote version.
+var PreferTheirs ConflictResolver = ConflictResolverFunc(func(c *Conflict) (interface{}, bool) {
+	return c.Theirs, true
+})
+
+// MergeOptions configure a #Merge().
+type MergeOptions struct {
+	// Resolver decides conflicts. If nil or if it keeps a conflict, mine is used and the conflict is reported.
+	Resolver ConflictResolver
+
+	// Identity returns the key of an array element. If all elements of an array version have a unique key,
+	// the elements are matched by it instead of by their position in the longest common subsequence.
+	Identity func(path Path, element interface{}) (string, bool)
+}
+
+// IdentityByKey returns an identity for MergeOptions which uses the given member of object elements,
+// like an id.
+func IdentityByKey(name string) func(path Path, element interface{}) (string, bool) {
+	return func(path Path, element interface{}) (string, bool) {
+		obj, ok := toObj(element)
+		if !ok || !obj.Has(name) || obj.IsNull(name) {
+			return "", false
+		}
+		return ToString(obj.Get(name)), true
+	}
+}
+
+// Merge performs a three-way merge of two versions, which have been derived from a common base. Changes
+// which have been made only on one side or equally on both sides are applied. Objects are merged per key and
+// arrays per element, see MergeOptions. Any other differing change is a conflict, which is given to the
+// resolver. The unresolved conflicts are returned in document order and the merged object contains mine for
+// them. The base may be nil, the versions are not modified and the merged object is a deep copy.
+func Merge(base, mine, theirs Obj, opts MergeOptions) (Obj, []*Conflict) {
+	m := &merger{opts: opts}
+	res := m.object(Path{}, toObjOrEmpty(base), toObjOrEmpty(mine), toObjOrEmpty(theirs))
+	return res, m.conflicts
+}
+
+func toObjOrEmpty(obj Obj) Obj {
+	if obj == nil {
+		return Object{}
+	}
+	return obj
+}
+
+type merger struct {
+	opts      MergeOptions
+	conflicts []*Conflict
+}
+
+func (s *merger) same(a, b interface{}) bool {
+	if isMissing(a) || isMissing(b) {
+		return isMissing(a) && isMissing(b)
+	}
+	return EqualValues(a, b, EqualOptions{})
+}
+
+func (s *merger) take(v interface{}) interface{} {
+	if isMissing(v) {
+		return v
+	}
+	return cloneValue(v)
+}
+
+// value merges a single value, where each version may be Missing
+func (s *merger) value(path Path, b, m, t interface{}) interface{} {
+	switch {
+	case s.same(m, t):
+		return s.take(m)
+	case s.same(b, m):
+		return s.take(t)
+	case s.same(b, t):
+		return s.take(m)
+	}
+
+	if mo, ok := toObj(m); ok {
+		if to, ok := toObj(t); ok {
+			bo, ok := toObj(b)
+			if !ok {
+				bo = Object{}
+			}
+			return s.object(path, bo, mo, to)
+		}
+	}
+
+	if ma, ok := toArr(m); ok && !isNil(m) {
+		if ta, ok := toArr(t); ok && !isNil(t) {
+			ba, ok := toArr(b)
+			if !ok || isNil(b) {
+				ba = &Array{}
+			}
+			return s.array(path, ba, ma, ta)
+		}
+	}
+
+	return s.conflict(&Conflict{Path: path, Base: b, Mine: m, Theirs: t})
+}
+
+// conflict asks the resolver and falls back to mine
+func (s *merger) conflict(c *Conflict) interface{} {
+	if s.opts.Resolver != nil {
+		if v, ok := s.opts.Resolver.Resolve(c); ok {
+			return s.take(v)
+		}
+	}
+	s.conflicts = append(s.conflicts, c)
+	return s.take(c.Mine)
+}
+
+func member(obj Obj, name string) interface{} {
+	if !obj.Has(name) {
+		return Missing
+	}
+	return obj.Get(name)
+}
+
+func (s *merger) object(path Path, b, m, t Obj) Object {
+	keys := map[string]bool{}
+	for _, obj := range []Obj{b, m, t} {
+		list := obj.Keys()
+		for i := 0; i < list.Size(); i++ {
+			keys[list.Get(i)] = true
+		}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	res := Object{}
+	for _, k := range sorted {
+		v := s.value(path.Child(k), member(b, k), member(m, k), member(t, k))
+		if !isMissing(v) {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func elements(arr Arr) []interface{} {
+	res := make([]interface{}, arr.Size())
+	for i := range res {
+		res[i] = arr.Get(i)
+	}
+	return res
+}
+
+func (s *merger) array(path Path, b, m, t Arr) *Array {
+	base, mine, theirs := elements(b), elements(m), elements(t)
+	if s.opts.Identity != nil {
+		if res, ok := s.arrayByIdentity(path, base, mine, theirs); ok {
+			return res
+		}
+	}
+
+	// diff3: stable elements are matched in all versions, the chunks in between are merged as a whole
+	mm, mt := s.lcs(base, mine), s.lcs(base, theirs)
+	res := &Array{}
+	i, j, k := 0, 0, 0
+	for {
+		next := i
+		for next < len(base) && (mm[next] < 0 || mt[next] < 0) {
+			next++
+		}
+		jm, kt := len(mine), len(theirs)
+		if next < len(base) {
+			jm, kt = mm[next], mt[next]
+		}
+		s.chunk(path, res, base[i:next], mine[j:jm], theirs[k:kt])
+		if next == len(base) {
+			return res
+		}
+		*res = append(*res, s.take(mine[jm]))
+		i, j, k = next+1, jm+1, kt+1
+	}
+}
+
+func (s *merger) sameElements(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !s.same(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *merger) chunk(path Path, res *Array, b, m, t []interface{}) {
+	add := func(list []interface{}) {
+		for _, v := range list {
+			*res = append(*res, s.take(v))
+		}
+	}
+
+	switch {
+	case s.sameElements(m, t):
+		add(m)
+	case s.sameElements(b, m):
+		add(t)
+	case s.sameElements(b, t):
+		add(m)
+	case len(b) == len(m) && len(m) == len(t):
+		// changed in place, e.g. different fields of the same objects
+		for i := range b {
+			if v := s.value(path.Child(len(*res)), b[i], m[i], t[i]); !isMissing(v) {
+				*res = append(*res, v)
+			}
+		}
+	default:
+		c := &Conflict{Path: path.Child(len(*res)), Range: true}
+		c.Base, c.Mine, c.Theirs = arrayOf(b), arrayOf(m), arrayOf(t)
+		v := s.conflict(c)
+		if isMissing(v) {
+			return
+		}
+		if arr, ok := toArr(v); ok && !isNil(v) {
+			add(elements(arr))
+			return
+		}
+		*res = append(*res, v)
+	}
+}
+
+// arrayOf returns a shallow copy as *Array
+func arrayOf(list []interface{}) *Array {
+	res := append(Array{}, list...)
+	return &res
+}
+
+// lcs returns for each element of a the index of the matched element in b or -1
+func (s *merger) lcs(a, b []interface{}) []int {
+	n, m := len(a), len(b)
+	eq := make([]bool, n*m)
+	// length[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	length := make([][]int, n+1)
+	for i := range length {
+		length[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			eq[i*m+j] = s.same(a[i], b[j])
+			switch {
+			case eq[i*m+j]:
+				length[i][j] = length[i+1][j+1] + 1
+			case length[i+1][j] >= length[i][j+1]:
+				length[i][j] = length[i+1][j]
+			default:
+				length[i][j] = length[i][j+1]
+			}
+		}
+	}
+
+	res := make([]int, n)
+	for i := range res {
+		res[i] = -1
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case eq[i*m+j]:
+			res[i] = j
+			i++
+			j++
+		case length[i+1][j] >= length[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return res
+}
+
+// identities returns the keys of all elements or false, if an element has no key or keys are not unique
+func (s *merger) identities(path Path, list []interface{}) ([]string, map[string]interface{}, bool) {
+	ids := make([]string, len(list))
+	byID := make(map[string]interface{}, len(list))
+	for i, v := range list {
+		id, ok := s.opts.Identity(path.Child(i), v)
+		if !ok {
+			return nil, nil, false
+		}
+		if _, dup := byID[id]; dup {
+			return nil, nil, false
+		}
+		ids[i] = id
+		byID[id] = v
+	}
+	return ids, byID, true
+}
+
+// arrayByIdentity keeps the order of mine and inserts new elements of theirs after their predecessor
+func (s *merger) arrayByIdentity(path Path, base, mine, theirs []interface{}) (*Array, bool) {
+	_, bs, ok1 := s.identities(path, base)
+	ms, mByID, ok2 := s.identities(path, mine)
+	ts, tByID, ok3 := s.identities(path, theirs)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, false
+	}
+
+	order := append([]string(nil), ms...)
+	for k, id := range ts {
+		if _, ok := mByID[id]; !ok {
+			// the position after the nearest predecessor, which is still part of the order
+			pos := 0
+			for j := k - 1; j >= 0 && pos == 0; j-- {
+				for i, o := range order {
+					if o == ts[j] {
+						pos = i + 1
+						break
+					}
+				}
+			}
+			order = append(order[:pos], append([]string{id}, order[pos:]...)...)
+		}
+	}
+
+	get := func(m map[string]interface{}, id string) interface{} {
+		if v, ok := m[id]; ok {
+			return v
+		}
+		return Missing
+	}
+
+	res := &Array{}
+	for _, id := range order {
+		if v := s.value(path.Child(len(*res)), get(bs, id), get(mByID, id), get(tByID, id)); !isMissing(v) {
+			*res = append(*res, v)
+		}
+	}
+	return res, true
+}
@@ -0,0 +1,89 @@
+package xobj
+
+import (
+	"testing"
+)
+
+func parseObj(t *testing.T, str string) Obj {
+	t.Helper()
+	obj, err := Parse([]byte(str))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func TestMerge(t *testing.T) {
+	base := parseObj(t, `{"title":"a","tags":["x","y","z"],"meta":{"a":1,"b":2},"gone":true}`)
+	mine := parseObj(t, `{"title":"b","tags":["w","x","y","z"],"meta":{"a":10,"b":2},"gone":true}`)
+	theirs := parseObj(t, `{"title":"a","tags":["x","y","z","v"],"meta":{"a":1,"b":20}}`)
+
+	res, conflicts := Merge(base, mine, theirs, MergeOptions{})
+	if len(conflicts) != 0 {
+		t.Fatal("unexpected", conflicts)
+	}
+	expected := `{"meta":{"a":10,"b":20},"tags":["w","x","y","z","v"],"title":"b"}`
+	if res.String() != expected {
+		t.Fatal("unexpected", res.String())
+	}
+	if mine.OptString("title", "") != "b" || Opt[int64](theirs, "meta.b", 0) != 20 {
+		t.Fatal("versions have been modified")
+	}
+}
+
+func TestMerge_Conflicts(t *testing.T) {
+	base := parseObj(t, `{"title":"a","list":[1,2,3],"del":{"x":1}}`)
+	mine := parseObj(t, `{"title":"b","list":[1,4,3],"del":{"x":2}}`)
+	theirs := parseObj(t, `{"title":"c","list":[1,5,6,3]}`)
+
+	res, conflicts := Merge(base, mine, theirs, MergeOptions{})
+	if len(conflicts) != 3 {
+		t.Fatal("unexpected", conflicts)
+	}
+	paths := []string{"del", "list[1]", "title"}
+	for i, c := range conflicts {
+		if c.PathString() != paths[i] {
+			t.Fatal("unexpected", i, c)
+		}
+	}
+	if !isMissing(conflicts[0].Theirs) || !conflicts[1].Range || ToString(conflicts[1].Theirs) != "[5,6]" {
+		t.Fatal("unexpected", conflicts)
+	}
+	if res.String() != `{"del":{"x":2},"list":[1,4,3],"title":"b"}` {
+		t.Fatal("unexpected", res.String())
+	}
+
+	res, conflicts = Merge(base, mine, theirs, MergeOptions{Resolver: PreferTheirs})
+	if len(conflicts) != 0 || res.String() != `{"list":[1,5,6,3],"title":"c"}` {
+		t.Fatal("unexpected", res.String(), conflicts)
+	}
+}
+
+func TestMerge_Identity(t *testing.T) {
+	base := parseObj(t, `{"items":[{"id":1,"n":"a"},{"id":2,"n":"b"},{"id":3,"n":"c"}]}`)
+	mine := parseObj(t, `{"items":[{"id":2,"n":"B"},{"id":1,"n":"a"},{"id":3,"n":"c"}]}`)
+	theirs := parseObj(t, `{"items":[{"id":1,"n":"a","x":true},{"id":2,"n":"b"},{"id":4,"n":"d"}]}`)
+
+	res, conflicts := Merge(base, mine, theirs, MergeOptions{Identity: IdentityByKey("id")})
+	if len(conflicts) != 0 {
+		t.Fatal("unexpected", conflicts)
+	}
+	expected := `{"items":[{"id":2,"n":"B"},{"id":4,"n":"d"},{"id":1,"n":"a","x":true}]}`
+	if res.String() != expected {
+		t.Fatal("unexpected", res.String())
+	}
+}
+
+func TestMerge_IdentityDeletedPredecessor(t *testing.T) {
+	base := parseObj(t, `{"items":[{"id":1},{"id":2},{"id":3}]}`)
+	mine := parseObj(t, `{"items":[{"id":1},{"id":3}]}`)
+	theirs := parseObj(t, `{"items":[{"id":1},{"id":2},{"id":4},{"id":3}]}`)
+
+	res, conflicts := Merge(base, mine, theirs, MergeOptions{Identity: IdentityByKey("id")})
+	if len(conflicts) != 0 {
+		t.Fatal("unexpected", conflicts)
+	}
+	if res.String() != `{"items":[{"id":1},{"id":4},{"id":3}]}` {
+		t.Fatal("unexpected", res.String())
+	}
+}
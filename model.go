@@ -1,10 +1,12 @@
 package xobj
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"math"
 	"strconv"
+	"time"
 )
 
 // StrList is used instead of a slice, to be directly compatible with gomobile.
@@ -75,6 +77,41 @@ type Obj interface {
 	// PutString removes the existing field and replaces its value. Returns the object for a builder pattern.
 	PutString(name string, value string) Obj
 
+	// AsTime tries to convert the associated value into a time, otherwise returns an error. Strings are parsed
+	// using the configured layouts (see #SetTimeLayouts()) and numbers are interpreted as unix seconds or
+	// milliseconds.
+	AsTime(name string) (time.Time, error)
+
+	// OptTime tries to convert the associated value into a time or returns the fallback
+	OptTime(name string, fallback time.Time) time.Time
+
+	// PutTime removes the existing field and replaces its value with a RFC 3339 string. Returns the object for
+	// a builder pattern.
+	PutTime(name string, value time.Time) Obj
+
+	// AsDuration tries to convert the associated value into a duration, otherwise returns an error. Strings
+	// may be go durations (1h30m) or ISO 8601 durations (PT1H30M), numbers are interpreted as seconds.
+	AsDuration(name string) (time.Duration, error)
+
+	// OptDuration tries to convert the associated value into a duration or returns the fallback
+	OptDuration(name string, fallback time.Duration) time.Duration
+
+	// PutDuration removes the existing field and replaces its value with a go duration string. Returns the
+	// object for a builder pattern.
+	PutDuration(name string, value time.Duration) Obj
+
+	// AsBytes tries to convert the associated value into a byte slice, otherwise returns an error. Strings are
+	// decoded from hex or from standard or url-safe base64.
+	AsBytes(name string) ([]byte, error)
+
+	// OptBytes tries to convert the associated value into a byte slice or returns the fallback
+	OptBytes(name string, fallback []byte) []byte
+
+	// PutBytes removes the existing field and replaces its value with a copy of the slice. It is kept as
+	// binary, so that binary formats can encode it natively, json serializes it as standard base64.
+	// Returns the object for a builder pattern.
+	PutBytes(name string, value []byte) Obj
+
 	// AsObject returns the value as an Obj, if the type matches, otherwise returns an error
 	AsObject(name string) (Obj, error)
 
@@ -171,6 +208,49 @@ type Arr interface {
 	// AddString appends the value and returns the array.
 	AddString(value string) Arr
 
+	// AsTime tries to convert the associated value into a time, otherwise returns an error. Strings are parsed
+	// using the configured layouts (see #SetTimeLayouts()) and numbers are interpreted as unix seconds or
+	// milliseconds.
+	AsTime(idx int) (time.Time, error)
+
+	// OptTime tries to convert the associated value into a time or returns the fallback
+	OptTime(idx int, fallback time.Time) time.Time
+
+	// PutTime replaces the value at the given index with a RFC 3339 string. Returns the array for a builder
+	// pattern. Panics if idx is out of bounds.
+	PutTime(idx int, value time.Time) Arr
+
+	// AddTime appends the value as a RFC 3339 string and returns the array.
+	AddTime(value time.Time) Arr
+
+	// AsDuration tries to convert the associated value into a duration, otherwise returns an error. Strings
+	// may be go durations (1h30m) or ISO 8601 durations (PT1H30M), numbers are interpreted as seconds.
+	AsDuration(idx int) (time.Duration, error)
+
+	// OptDuration tries to convert the associated value into a duration or returns the fallback
+	OptDuration(idx int, fallback time.Duration) time.Duration
+
+	// PutDuration replaces the value at the given index with a go duration string. Returns the array for a
+	// builder pattern. Panics if idx is out of bounds.
+	PutDuration(idx int, value time.Duration) Arr
+
+	// AddDuration appends the value as a go duration string and returns the array.
+	AddDuration(value time.Duration) Arr
+
+	// AsBytes tries to convert the associated value into a byte slice, otherwise returns an error. Strings are
+	// decoded from hex or from standard or url-safe base64.
+	AsBytes(idx int) ([]byte, error)
+
+	// OptBytes tries to convert the associated value into a byte slice or returns the fallback
+	OptBytes(idx int, fallback []byte) []byte
+
+	// PutBytes replaces the value at the given index with a copy of the slice. Returns the array for a
+	// builder pattern. Panics if idx is out of bounds.
+	PutBytes(idx int, value []byte) Arr
+
+	// AddBytes appends a copy of the slice and returns the array.
+	AddBytes(value []byte) Arr
+
 	// AsObject returns the value as an Obj, if the type matches, otherwise returns an error
 	AsObject(idx int) (Obj, error)
 
@@ -217,6 +297,8 @@ func ToString(any interface{}) string {
 		return strconv.FormatInt(t, 10)
 	case bool:
 		return strconv.FormatBool(t)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(t)
 	case fmt.Stringer:
 		return t.String()
 	case []interface{}:
@@ -301,44 +383,35 @@ func NewArr() Arr {
 
 // UnwrapObj allocates new maps and slices for Obj and Arr instances. We do not cast recursively
 // because it would change internal types, values and pointers while running causing all sorts of bugs.
+// The given obj is not modified.
 func UnwrapObj(obj Obj) map[string]interface{} {
 	res := make(map[string]interface{})
 	keys := obj.Keys()
 	for i := 0; i < keys.Size(); i++ {
 		k := keys.Get(i)
-		c, err := obj.AsObject(k)
-		if err == nil {
-			res[keys.Get(i)] = UnwrapObj(c)
-			continue
-		}
-
-		a, err := obj.AsArray(k)
-		if err == nil {
-			res[keys.Get(i)] = UnwrapArr(a)
-			continue
-		}
-		res[k] = obj.Get(k)
+		res[k] = unwrapValue(obj.Get(k))
 	}
 	return res
 }
 
 // UnwrapArr allocates new maps and slices for Obj and Arr instances. We do not cast recursively
 // because it would change internal types, values and pointers while running causing all sorts of bugs.
+// The given arr is not modified.
 func UnwrapArr(arr Arr) []interface{} {
 	res := make([]interface{}, arr.Size())
 	for i := 0; i < arr.Size(); i++ {
-		c, err := arr.AsObject(i)
-		if err == nil {
-			res[i] = UnwrapObj(c)
-			continue
-		}
-
-		a, err := arr.AsArray(i)
-		if err == nil {
-			res[i] = UnwrapArr(a)
-			continue
-		}
-		res[i] = arr.Get(i)
+		res[i] = unwrapValue(arr.Get(i))
 	}
 	return res
 }
+
+// unwrapValue copies wrapped and raw containers into raw maps and slices
+func unwrapValue(v interface{}) interface{} {
+	if obj, ok := toObj(v); ok {
+		return UnwrapObj(obj)
+	}
+	if arr, ok := toArr(v); ok {
+		return UnwrapArr(arr)
+	}
+	return v
+}
@@ -3,8 +3,8 @@ package xobj
 import (
 	"encoding/json"
 	"fmt"
-	"reflect"
 	"strconv"
+	"time"
 )
 
 // wrapper is used to avoid type assertion on hidden types. This is a problem
@@ -74,7 +74,8 @@ func (o Object) AsInt64(name string) (int64, error) {
 	if !ok {
 		return 0, unknownFieldName(name)
 	}
-	return asInt64(v)
+	res, err := asInt64(v)
+	return res, withPath(Path{name}, err)
 }
 
 func (o Object) OptInt64(name string, fallback int64) int64 {
@@ -95,7 +96,8 @@ func (o Object) AsBool(name string) (bool, error) {
 	if !ok {
 		return false, unknownFieldName(name)
 	}
-	return asBool(v)
+	res, err := asBool(v)
+	return res, withPath(Path{name}, err)
 }
 
 func (o Object) OptBool(name string, fallback bool) bool {
@@ -117,7 +119,8 @@ func (o Object) AsFloat64(name string) (float64, error) {
 	if !ok {
 		return 0, unknownFieldName(name)
 	}
-	return asFloat64(v)
+	res, err := asFloat64(v)
+	return res, withPath(Path{name}, err)
 }
 
 func (o Object) OptFloat64(name string, fallback float64) float64 {
@@ -138,7 +141,8 @@ func (o Object) AsString(name string) (string, error) {
 	if !ok {
 		return "", unknownFieldName(name)
 	}
-	return asString(v)
+	res, err := asString(v)
+	return res, withPath(Path{name}, err)
 }
 
 func (o Object) OptString(name string, fallback string) string {
@@ -154,6 +158,72 @@ func (o Object) PutString(name string, value string) Obj {
 	return o
 }
 
+func (o Object) AsTime(name string) (time.Time, error) {
+	v, ok := o[name]
+	if !ok {
+		return time.Time{}, unknownFieldName(name)
+	}
+	res, err := asTime(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o Object) OptTime(name string, fallback time.Time) time.Time {
+	v, err := o.AsTime(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o Object) PutTime(name string, value time.Time) Obj {
+	o[name] = formatTime(value)
+	return o
+}
+
+func (o Object) AsDuration(name string) (time.Duration, error) {
+	v, ok := o[name]
+	if !ok {
+		return 0, unknownFieldName(name)
+	}
+	res, err := asDuration(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o Object) OptDuration(name string, fallback time.Duration) time.Duration {
+	v, err := o.AsDuration(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o Object) PutDuration(name string, value time.Duration) Obj {
+	o[name] = formatDuration(value)
+	return o
+}
+
+func (o Object) AsBytes(name string) ([]byte, error) {
+	v, ok := o[name]
+	if !ok {
+		return nil, unknownFieldName(name)
+	}
+	res, err := asBytes(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o Object) OptBytes(name string, fallback []byte) []byte {
+	v, err := o.AsBytes(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o Object) PutBytes(name string, value []byte) Obj {
+	o[name] = copyBytes(value)
+	return o
+}
+
 func (o Object) AsObject(name string) (Obj, error) {
 	v, ok := o[name]
 	if !ok {
@@ -162,7 +232,7 @@ func (o Object) AsObject(name string) (Obj, error) {
 	if obj, ok := v.(Obj); ok {
 		return obj, nil
 	}
-	return nil, fmt.Errorf("%s is not an object (%v)", name, reflect.TypeOf(v))
+	return nil, withPath(Path{name}, typeMismatch(v, "object"))
 }
 
 func (o Object) OptObject(name string) Obj {
@@ -197,7 +267,7 @@ func (o Object) AsArray(name string) (Arr, error) {
 		o[name] = &tmp
 		return &tmp, nil
 	}
-	return nil, fmt.Errorf("value in field '%s' is not an array, is type '%v'", name, reflect.TypeOf(v))
+	return nil, withPath(Path{name}, typeMismatch(v, "array"))
 }
 
 func (o Object) OptArray(name string) Arr {
@@ -262,7 +332,8 @@ func (a *Array) AsInt64(idx int) (int64, error) {
 	if idx < 0 || idx >= len(*a) {
 		return 0, outOfBounds(*a, idx)
 	}
-	return asInt64((*a)[idx])
+	res, err := asInt64((*a)[idx])
+	return res, withPath(Path{idx}, err)
 }
 
 func (a *Array) OptInt64(idx int, fallback int64) int64 {
@@ -287,7 +358,8 @@ func (a *Array) AsBool(idx int) (bool, error) {
 	if idx < 0 || idx >= len(*a) {
 		return false, outOfBounds(*a, idx)
 	}
-	return asBool((*a)[idx])
+	res, err := asBool((*a)[idx])
+	return res, withPath(Path{idx}, err)
 }
 
 func (a *Array) OptBool(idx int, fallback bool) bool {
@@ -312,7 +384,8 @@ func (a *Array) AsFloat64(idx int) (float64, error) {
 	if idx < 0 || idx >= len(*a) {
 		return 0, outOfBounds(*a, idx)
 	}
-	return asFloat64((*a)[idx])
+	res, err := asFloat64((*a)[idx])
+	return res, withPath(Path{idx}, err)
 }
 
 func (a *Array) OptFloat64(idx int, fallback float64) float64 {
@@ -337,7 +410,8 @@ func (a *Array) AsString(idx int) (string, error) {
 	if idx < 0 || idx >= len(*a) {
 		return "", outOfBounds(*a, idx)
 	}
-	return asString((*a)[idx])
+	res, err := asString((*a)[idx])
+	return res, withPath(Path{idx}, err)
 }
 
 func (a *Array) OptString(idx int, fallback string) string {
@@ -358,6 +432,84 @@ func (a *Array) AddString(value string) Arr {
 	return a
 }
 
+func (a *Array) AsTime(idx int) (time.Time, error) {
+	if idx < 0 || idx >= len(*a) {
+		return time.Time{}, outOfBounds(*a, idx)
+	}
+	res, err := asTime((*a)[idx])
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *Array) OptTime(idx int, fallback time.Time) time.Time {
+	v, err := a.AsTime(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *Array) PutTime(idx int, value time.Time) Arr {
+	(*a)[idx] = formatTime(value)
+	return a
+}
+
+func (a *Array) AddTime(value time.Time) Arr {
+	*a = append(*a, formatTime(value))
+	return a
+}
+
+func (a *Array) AsDuration(idx int) (time.Duration, error) {
+	if idx < 0 || idx >= len(*a) {
+		return 0, outOfBounds(*a, idx)
+	}
+	res, err := asDuration((*a)[idx])
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *Array) OptDuration(idx int, fallback time.Duration) time.Duration {
+	v, err := a.AsDuration(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *Array) PutDuration(idx int, value time.Duration) Arr {
+	(*a)[idx] = formatDuration(value)
+	return a
+}
+
+func (a *Array) AddDuration(value time.Duration) Arr {
+	*a = append(*a, formatDuration(value))
+	return a
+}
+
+func (a *Array) AsBytes(idx int) ([]byte, error) {
+	if idx < 0 || idx >= len(*a) {
+		return nil, outOfBounds(*a, idx)
+	}
+	res, err := asBytes((*a)[idx])
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *Array) OptBytes(idx int, fallback []byte) []byte {
+	v, err := a.AsBytes(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *Array) PutBytes(idx int, value []byte) Arr {
+	(*a)[idx] = copyBytes(value)
+	return a
+}
+
+func (a *Array) AddBytes(value []byte) Arr {
+	*a = append(*a, copyBytes(value))
+	return a
+}
+
 func (a *Array) AsObject(idx int) (Obj, error) {
 	if idx < 0 || idx >= len(*a) {
 		return nil, outOfBounds(*a, idx)
@@ -368,7 +520,7 @@ func (a *Array) AsObject(idx int) (Obj, error) {
 	if obj, ok := (*a)[idx].(map[string]interface{}); ok {
 		return Object(obj), nil
 	}
-	return nil, fmt.Errorf("element at index %d is not an object (%v)", idx, reflect.TypeOf((*a)[idx]))
+	return nil, withPath(Path{idx}, typeMismatch((*a)[idx], "object"))
 }
 
 func (a *Array) OptObject(idx int) Obj {
@@ -419,7 +571,7 @@ func (a *Array) AsArray(idx int) (Arr, error) {
 		return &tmp, nil
 	}
 
-	return nil, fmt.Errorf("value at index '%d' is not an array, but '%v'", idx, reflect.TypeOf(v))
+	return nil, withPath(Path{idx}, typeMismatch(v, "array"))
 }
 
 func (a *Array) OptArray(idx int) Arr {
@@ -453,14 +605,6 @@ func (a *Array) String() string {
 
 //==
 
-func unknownFieldName(name string) error {
-	return fmt.Errorf("unknown name %s", name)
-}
-
-func outOfBounds(slice []interface{}, idx int) error {
-	return fmt.Errorf("out of bounds %d, having %d", idx, len(slice))
-}
-
 func asFloat64(v interface{}) (float64, error) {
 
 	switch t := v.(type) {
@@ -491,7 +635,11 @@ func asFloat64(v interface{}) (float64, error) {
 
 	}
 
-	return strconv.ParseFloat(ToString(v), 64)
+	f, err := strconv.ParseFloat(ToString(v), 64)
+	if err != nil {
+		return 0, conversionFailed(v, "float64", err)
+	}
+	return f, nil
 }
 
 func asInt64(v interface{}) (int64, error) {
@@ -518,7 +666,11 @@ func asInt64(v interface{}) (int64, error) {
 		return int64(t), nil
 
 	}
-	return strconv.ParseInt(ToString(v), 10, 64)
+	i, err := strconv.ParseInt(ToString(v), 10, 64)
+	if err != nil {
+		return 0, conversionFailed(v, "int64", err)
+	}
+	return i, nil
 }
 
 func asBool(v interface{}) (bool, error) {
@@ -532,7 +684,11 @@ func asBool(v interface{}) (bool, error) {
 			return true, nil
 		}
 	}
-	return strconv.ParseBool(ToString(v))
+	b, err := strconv.ParseBool(ToString(v))
+	if err != nil {
+		return false, conversionFailed(v, "bool", err)
+	}
+	return b, nil
 }
 
 func asString(v interface{}) (string, error) {
@@ -540,11 +696,11 @@ func asString(v interface{}) (string, error) {
 	case string:
 		return t, nil
 	case map[string]interface{}:
-		return "", fmt.Errorf("won't convert Obj to string")
+		return "", typeMismatch(v, "string")
 	case []interface{}:
-		return "", fmt.Errorf("won't convert Arr to string")
+		return "", typeMismatch(v, "string")
 	case *[]interface{}: // we are replacing slices with pointer to slice whenever accessed
-		return "", fmt.Errorf("won't convert Arr to string")
+		return "", typeMismatch(v, "string")
 	}
 	return ToString(v), nil
 }
@@ -0,0 +1,918 @@
+package xobj
+
+import (
+	"fmt"
+	"time"
+)
+
+// ChangeOp is the kind of a change, named like the operations of a JSON Patch (RFC 6902).
+type ChangeOp int
+
+const (
+	// ChangeAdd is a new object member or an inserted array element
+	ChangeAdd ChangeOp = iota + 1
+	// ChangeReplace is a new value of an existing object member or array element
+	ChangeReplace
+	// ChangeRemove is a removed object member or array element, subsequent elements move to the front
+	ChangeRemove
+)
+
+func (c ChangeOp) String() string {
+	switch c {
+	case ChangeAdd:
+		return "add"
+	case ChangeReplace:
+		return "replace"
+	case ChangeRemove:
+		return "remove"
+	}
+	return fmt.Sprintf("ChangeOp(%d)", int(c))
+}
+
+// A ChangeEvent describes a single modification of an ObservableObj. The path is always absolute, even
+// if the listener has been subscribed through a nested wrapper. Old is nil for additions and New is nil for
+// removals. Containers are detached copies, so modifying them has no effect on the document.
+type ChangeEvent struct {
+	Path Path
+	Op   ChangeOp
+	Old  interface{}
+	New  interface{}
+}
+
+// PathString returns the path in the notation of #ParsePath(), which is easier to use with gomobile.
+func (e *ChangeEvent) PathString() string {
+	return e.Path.String()
+}
+
+func (e *ChangeEvent) String() string {
+	return fmt.Sprintf("%s %s: %s -> %s", e.Op, e.Path, ToString(e.Old), ToString(e.New))
+}
+
+// ChangeList is used instead of a slice, to be directly compatible with gomobile.
+type ChangeList interface {
+	Size() int
+	Get(idx int) *ChangeEvent
+}
+
+var _ ChangeList = (ChangeEvents)(nil)
+
+// ChangeEvents is just a simple slice of events, so you can perform an efficient type conversion.
+type ChangeEvents []*ChangeEvent
+
+func (c ChangeEvents) Size() int {
+	return len(c)
+}
+
+func (c ChangeEvents) Get(idx int) *ChangeEvent {
+	return c[idx]
+}
+
+// A ChangeListener is notified after changes have been applied. Outside of a transaction each change is
+// delivered on its own, otherwise all changes are delivered at once when the outermost transaction commits.
+// Listeners are invoked synchronously and may modify the document, which causes further notifications.
+type ChangeListener interface {
+	OnChange(events ChangeList)
+}
+
+// ChangeListenerFunc allows to use an ordinary function as a ChangeListener.
+type ChangeListenerFunc func(events ChangeList)
+
+func (f ChangeListenerFunc) OnChange(events ChangeList) {
+	f(events)
+}
+
+// A Subscription connects a ChangeListener with an ObservableObj.
+type Subscription struct {
+	root     *observeRoot
+	prefix   Path
+	listener ChangeListener
+}
+
+// Cancel removes the listener, so that it receives no further events. Cancelling twice has no effect.
+func (s *Subscription) Cancel() {
+	for i, e := range s.root.subs {
+		if e == s {
+			s.root.subs = append(s.root.subs[:i:i], s.root.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// matches is true, if the change is below the prefix or replaces a parent of it
+func (s *Subscription) matches(path Path) bool {
+	n := len(s.prefix)
+	if len(path) < n {
+		n = len(path)
+	}
+	for i := 0; i < n; i++ {
+		if s.prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// observeRoot is the shared state of an ObservableObj and all wrappers which have been handed out by it
+type observeRoot struct {
+	// data contains only Object and *Array containers, like the data of a SyncObj
+	data       Object
+	subs       []*Subscription
+	depth      int
+	pending    []*ChangeEvent
+	delivering bool
+}
+
+func (r *observeRoot) emit(e *ChangeEvent) {
+	r.pending = append(r.pending, e)
+	if r.depth == 0 {
+		r.flush()
+	}
+}
+
+// flush delivers the pending events, including those which are caused by the listeners themselves
+func (r *observeRoot) flush() {
+	if r.delivering {
+		return
+	}
+	r.delivering = true
+	defer func() { r.delivering = false }()
+
+	for len(r.pending) > 0 {
+		events := r.pending
+		r.pending = nil
+		// listeners may cancel subscriptions while being notified
+		subs := append([]*Subscription(nil), r.subs...)
+		for _, s := range subs {
+			var list ChangeEvents
+			for _, e := range events {
+				if s.matches(e.Path) {
+					list = append(list, e)
+				}
+			}
+			if len(list) > 0 {
+				s.listener.OnChange(list)
+			}
+		}
+	}
+}
+
+// apply performs a change at the path and emits it, which is used to replay recorded changes. In contrast
+// to the Arr interface, adding to an array inserts at the index.
+func (r *observeRoot) apply(op ChangeOp, path Path, value interface{}) error {
+	if len(path) == 0 {
+		return fmt.Errorf("cannot change the root object")
+	}
+	parentPath, seg := path[:len(path)-1], path[len(path)-1]
+	parent, err := Lookup(r.data, parentPath)
+	if err != nil {
+		return err
+	}
+
+	value = syncValue(value)
+	var e *ChangeEvent
+	switch c := parent.(type) {
+	case Object:
+		key, ok := seg.(string)
+		if !ok {
+			return withPath(parentPath, typeMismatch(c, "array"))
+		}
+		old, hadOld := c[key]
+		if op == ChangeRemove {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		v, hasValue := c[key]
+		e = changeOf(path, old, hadOld, v, hasValue)
+	case *Array:
+		idx, ok := seg.(int)
+		if !ok {
+			return withPath(parentPath, typeMismatch(c, "object"))
+		}
+		size := len(*c)
+		if op == ChangeAdd {
+			size++
+		}
+		if idx < 0 || idx >= size {
+			return withPath(parentPath, outOfBoundsAt(idx, len(*c)))
+		}
+		switch op {
+		case ChangeAdd:
+			insertAt(c, idx, value)
+			e = changeOf(path, nil, false, value, true)
+		case ChangeRemove:
+			old := (*c)[idx]
+			c.Remove(idx)
+			e = changeOf(path, old, true, nil, false)
+		default:
+			old := (*c)[idx]
+			(*c)[idx] = value
+			e = changeOf(path, old, true, value, true)
+		}
+	default:
+		return withPath(parentPath, typeMismatch(parent, "object"))
+	}
+
+	if e != nil {
+		r.emit(e)
+	}
+	return nil
+}
+
+// detached returns a copy of containers, so that listeners cannot modify the document unnoticed
+func detached(v interface{}) interface{} {
+	if isContainer(v) {
+		return syncValue(v)
+	}
+	return v
+}
+
+// changeOf creates the event for a member or element, which has been replaced or is nil if nothing changed
+func changeOf(path Path, old interface{}, hadOld bool, value interface{}, hasValue bool) *ChangeEvent {
+	switch {
+	case !hadOld && !hasValue:
+		return nil
+	case !hadOld:
+		return &ChangeEvent{Path: path, Op: ChangeAdd, New: detached(value)}
+	case !hasValue:
+		return &ChangeEvent{Path: path, Op: ChangeRemove, Old: old}
+	case sameValue(old, value):
+		return nil
+	}
+	return &ChangeEvent{Path: path, Op: ChangeReplace, Old: old, New: detached(value)}
+}
+
+//=
+
+var _ Obj = (*ObservableObj)(nil)
+
+// An ObservableObj is an Obj which notifies subscribed listeners about all modifications. Like a SyncObj,
+// nested wrappers address their value by path and values are copied when they are put, so that every
+// modification is noticed. It is not thread safe, use it e.g. from the ui thread only.
+type ObservableObj struct {
+	root *observeRoot
+	path Path
+}
+
+// NewObservableObj creates an ObservableObj from a deep copy of the given object, which may be nil.
+func NewObservableObj(obj Obj) *ObservableObj {
+	data := Object{}
+	if obj != nil {
+		data = syncValue(obj).(Object)
+	}
+	return &ObservableObj{root: &observeRoot{data: data}}
+}
+
+// Subscribe registers the listener for all changes at or below the given path, which is relative to this
+// object and uses the notation of #ParsePath(). The empty path observes everything. Replacing or removing
+// a parent of the path is also delivered, because it changes the observed values as well.
+func (o *ObservableObj) Subscribe(path string, listener ChangeListener) (*Subscription, error) {
+	p, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	prefix := append(append(Path{}, o.path...), p...)
+	s := &Subscription{root: o.root, prefix: prefix, listener: listener}
+	o.root.subs = append(o.root.subs, s)
+	return s, nil
+}
+
+// Begin starts a transaction, whose changes are delivered together by the matching #Commit(). Transactions
+// can be nested and only the outermost commit delivers. Changes are applied immediately, there is no rollback.
+func (o *ObservableObj) Begin() {
+	o.root.depth++
+}
+
+// Commit ends a transaction started by #Begin().
+func (o *ObservableObj) Commit() {
+	if o.root.depth == 0 {
+		panic("xobj: commit without begin")
+	}
+	o.root.depth--
+	if o.root.depth == 0 {
+		o.root.flush()
+	}
+}
+
+// Batch invokes the function within a transaction.
+func (o *ObservableObj) Batch(fn func()) {
+	o.Begin()
+	defer o.Commit()
+	fn()
+}
+
+func (o *ObservableObj) obj() Object {
+	v, ok := resolvePath(o.root.data, o.path)
+	if !ok {
+		return nil
+	}
+	obj, _ := v.(Object)
+	return obj
+}
+
+func (o *ObservableObj) read(fn func(obj Object)) {
+	obj := o.obj()
+	if obj == nil {
+		obj = Object{}
+	}
+	fn(obj)
+}
+
+// write applies the modification of the named member and emits the according event
+func (o *ObservableObj) write(name string, fn func(obj Object)) {
+	obj := o.obj()
+	if obj == nil {
+		logger.Info(Fields{"msg": "write to vanished object dropped", "path": o.path.String()})
+		return
+	}
+	old, hadOld := obj[name]
+	fn(obj)
+	value, hasValue := obj[name]
+	if e := changeOf(o.path.Child(name), old, hadOld, value, hasValue); e != nil {
+		o.root.emit(e)
+	}
+}
+
+func (o *ObservableObj) wrap(name string, v interface{}) interface{} {
+	switch t := v.(type) {
+	case Object:
+		return &ObservableObj{root: o.root, path: o.path.Child(name)}
+	case *Array:
+		return &ObservableArr{root: o.root, path: o.path.Child(name)}
+	case []byte:
+		return copyBytes(t)
+	}
+	return v
+}
+
+func (o *ObservableObj) Keys() StrList {
+	var res StrList
+	o.read(func(obj Object) { res = obj.Keys() })
+	return res
+}
+
+func (o *ObservableObj) Get(name string) interface{} {
+	var res interface{}
+	o.read(func(obj Object) { res = o.wrap(name, obj.Get(name)) })
+	return res
+}
+
+func (o *ObservableObj) Put(name string, value interface{}) Obj {
+	value = syncValue(value)
+	o.write(name, func(obj Object) { obj.Put(name, value) })
+	return o
+}
+
+func (o *ObservableObj) Remove(name string) Obj {
+	o.write(name, func(obj Object) { obj.Remove(name) })
+	return o
+}
+
+func (o *ObservableObj) Has(name string) bool {
+	var res bool
+	o.read(func(obj Object) { res = obj.Has(name) })
+	return res
+}
+
+func (o *ObservableObj) IsNull(name string) bool {
+	var res bool
+	o.read(func(obj Object) { res = obj.IsNull(name) })
+	return res
+}
+
+func (o *ObservableObj) AsInt64(name string) (int64, error) {
+	var res int64
+	var err error
+	o.read(func(obj Object) { res, err = obj.AsInt64(name) })
+	return res, withPath(o.path, err)
+}
+
+func (o *ObservableObj) OptInt64(name string, fallback int64) int64 {
+	v, err := o.AsInt64(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *ObservableObj) PutInt64(name string, value int64) Obj {
+	o.write(name, func(obj Object) { obj.PutInt64(name, value) })
+	return o
+}
+
+func (o *ObservableObj) AsBool(name string) (bool, error) {
+	var res bool
+	var err error
+	o.read(func(obj Object) { res, err = obj.AsBool(name) })
+	return res, withPath(o.path, err)
+}
+
+func (o *ObservableObj) OptBool(name string, fallback bool) bool {
+	v, err := o.AsBool(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *ObservableObj) PutBool(name string, value bool) Obj {
+	o.write(name, func(obj Object) { obj.PutBool(name, value) })
+	return o
+}
+
+func (o *ObservableObj) AsFloat64(name string) (float64, error) {
+	var res float64
+	var err error
+	o.read(func(obj Object) { res, err = obj.AsFloat64(name) })
+	return res, withPath(o.path, err)
+}
+
+func (o *ObservableObj) OptFloat64(name string, fallback float64) float64 {
+	v, err := o.AsFloat64(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *ObservableObj) PutFloat64(name string, value float64) Obj {
+	o.write(name, func(obj Object) { obj.PutFloat64(name, value) })
+	return o
+}
+
+func (o *ObservableObj) AsString(name string) (string, error) {
+	var res string
+	var err error
+	o.read(func(obj Object) { res, err = obj.AsString(name) })
+	return res, withPath(o.path, err)
+}
+
+func (o *ObservableObj) OptString(name string, fallback string) string {
+	v, err := o.AsString(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *ObservableObj) PutString(name string, value string) Obj {
+	o.write(name, func(obj Object) { obj.PutString(name, value) })
+	return o
+}
+
+func (o *ObservableObj) AsTime(name string) (time.Time, error) {
+	var res time.Time
+	var err error
+	o.read(func(obj Object) { res, err = obj.AsTime(name) })
+	return res, withPath(o.path, err)
+}
+
+func (o *ObservableObj) OptTime(name string, fallback time.Time) time.Time {
+	v, err := o.AsTime(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *ObservableObj) PutTime(name string, value time.Time) Obj {
+	o.write(name, func(obj Object) { obj.PutTime(name, value) })
+	return o
+}
+
+func (o *ObservableObj) AsDuration(name string) (time.Duration, error) {
+	var res time.Duration
+	var err error
+	o.read(func(obj Object) { res, err = obj.AsDuration(name) })
+	return res, withPath(o.path, err)
+}
+
+func (o *ObservableObj) OptDuration(name string, fallback time.Duration) time.Duration {
+	v, err := o.AsDuration(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *ObservableObj) PutDuration(name string, value time.Duration) Obj {
+	o.write(name, func(obj Object) { obj.PutDuration(name, value) })
+	return o
+}
+
+func (o *ObservableObj) AsBytes(name string) ([]byte, error) {
+	var res []byte
+	var err error
+	o.read(func(obj Object) {
+		res, err = obj.AsBytes(name)
+	})
+	return res, withPath(o.path, err)
+}
+
+func (o *ObservableObj) OptBytes(name string, fallback []byte) []byte {
+	v, err := o.AsBytes(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *ObservableObj) PutBytes(name string, value []byte) Obj {
+	o.write(name, func(obj Object) { obj.PutBytes(name, value) })
+	return o
+}
+
+func (o *ObservableObj) AsObject(name string) (Obj, error) {
+	var err error
+	o.read(func(obj Object) { _, err = obj.AsObject(name) })
+	if err != nil {
+		return nil, withPath(o.path, err)
+	}
+	return &ObservableObj{root: o.root, path: o.path.Child(name)}, nil
+}
+
+func (o *ObservableObj) OptObject(name string) Obj {
+	if v, err := o.AsObject(name); err == nil {
+		return v
+	}
+	o.write(name, func(obj Object) { obj.Put(name, Object{}) })
+	return &ObservableObj{root: o.root, path: o.path.Child(name)}
+}
+
+func (o *ObservableObj) PutObject(name string, value Obj) Obj {
+	v := syncValue(value)
+	o.write(name, func(obj Object) { obj.Put(name, v) })
+	return o
+}
+
+func (o *ObservableObj) AsArray(name string) (Arr, error) {
+	var err error
+	o.read(func(obj Object) { _, err = obj.AsArray(name) })
+	if err != nil {
+		return nil, withPath(o.path, err)
+	}
+	return &ObservableArr{root: o.root, path: o.path.Child(name)}, nil
+}
+
+func (o *ObservableObj) OptArray(name string) Arr {
+	if v, err := o.AsArray(name); err == nil {
+		return v
+	}
+	o.write(name, func(obj Object) { obj.Put(name, &Array{}) })
+	return &ObservableArr{root: o.root, path: o.path.Child(name)}
+}
+
+func (o *ObservableObj) PutArray(name string, value Arr) Obj {
+	v := syncValue(value)
+	o.write(name, func(obj Object) { obj.Put(name, v) })
+	return o
+}
+
+func (o *ObservableObj) String() string {
+	var res string
+	o.read(func(obj Object) { res = obj.String() })
+	return res
+}
+
+//==
+
+var _ Arr = (*ObservableArr)(nil)
+
+// An ObservableArr is the Arr counterpart of an ObservableObj and can only be obtained from it.
+type ObservableArr struct {
+	root *observeRoot
+	path Path
+}
+
+func (a *ObservableArr) arr() *Array {
+	v, ok := resolvePath(a.root.data, a.path)
+	if !ok {
+		return nil
+	}
+	arr, _ := v.(*Array)
+	return arr
+}
+
+func (a *ObservableArr) read(fn func(arr *Array)) {
+	arr := a.arr()
+	if arr == nil {
+		arr = &Array{}
+	}
+	fn(arr)
+}
+
+// write applies a modification and emits the event returned by it
+func (a *ObservableArr) write(fn func(arr *Array) *ChangeEvent) {
+	arr := a.arr()
+	if arr == nil {
+		logger.Info(Fields{"msg": "write to vanished array dropped", "path": a.path.String()})
+		return
+	}
+	if e := fn(arr); e != nil {
+		a.root.emit(e)
+	}
+}
+
+// put replaces an element, which panics if the index is out of bounds
+func (a *ObservableArr) put(idx int, fn func(arr *Array)) {
+	a.write(func(arr *Array) *ChangeEvent {
+		old := arr.Get(idx)
+		fn(arr)
+		return changeOf(a.path.Child(idx), old, true, (*arr)[idx], true)
+	})
+}
+
+// add appends an element
+func (a *ObservableArr) add(fn func(arr *Array)) {
+	a.write(func(arr *Array) *ChangeEvent {
+		fn(arr)
+		idx := arr.Size() - 1
+		return changeOf(a.path.Child(idx), nil, false, (*arr)[idx], true)
+	})
+}
+
+func (a *ObservableArr) wrap(idx int, v interface{}) interface{} {
+	switch t := v.(type) {
+	case Object:
+		return &ObservableObj{root: a.root, path: a.path.Child(idx)}
+	case *Array:
+		return &ObservableArr{root: a.root, path: a.path.Child(idx)}
+	case []byte:
+		return copyBytes(t)
+	}
+	return v
+}
+
+func (a *ObservableArr) Size() int {
+	var res int
+	a.read(func(arr *Array) { res = arr.Size() })
+	return res
+}
+
+func (a *ObservableArr) Get(idx int) interface{} {
+	var res interface{}
+	a.read(func(arr *Array) { res = a.wrap(idx, arr.Get(idx)) })
+	return res
+}
+
+func (a *ObservableArr) Put(idx int, value interface{}) Arr {
+	value = syncValue(value)
+	a.put(idx, func(arr *Array) { arr.Put(idx, value) })
+	return a
+}
+
+func (a *ObservableArr) IsNull(idx int) bool {
+	var res bool
+	a.read(func(arr *Array) { res = arr.IsNull(idx) })
+	return res
+}
+
+func (a *ObservableArr) Remove(idx int) Arr {
+	a.write(func(arr *Array) *ChangeEvent {
+		old := arr.Get(idx)
+		arr.Remove(idx)
+		return changeOf(a.path.Child(idx), old, true, nil, false)
+	})
+	return a
+}
+
+func (a *ObservableArr) AsInt64(idx int) (int64, error) {
+	var res int64
+	var err error
+	a.read(func(arr *Array) { res, err = arr.AsInt64(idx) })
+	return res, withPath(a.path, err)
+}
+
+func (a *ObservableArr) OptInt64(idx int, fallback int64) int64 {
+	v, err := a.AsInt64(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *ObservableArr) PutInt64(idx int, value int64) Arr {
+	a.put(idx, func(arr *Array) { arr.PutInt64(idx, value) })
+	return a
+}
+
+func (a *ObservableArr) AddInt64(value int64) Arr {
+	a.add(func(arr *Array) { arr.AddInt64(value) })
+	return a
+}
+
+func (a *ObservableArr) AsBool(idx int) (bool, error) {
+	var res bool
+	var err error
+	a.read(func(arr *Array) { res, err = arr.AsBool(idx) })
+	return res, withPath(a.path, err)
+}
+
+func (a *ObservableArr) OptBool(idx int, fallback bool) bool {
+	v, err := a.AsBool(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *ObservableArr) PutBool(idx int, value bool) Arr {
+	a.put(idx, func(arr *Array) { arr.PutBool(idx, value) })
+	return a
+}
+
+func (a *ObservableArr) AddBool(value bool) Arr {
+	a.add(func(arr *Array) { arr.AddBool(value) })
+	return a
+}
+
+func (a *ObservableArr) AsFloat64(idx int) (float64, error) {
+	var res float64
+	var err error
+	a.read(func(arr *Array) { res, err = arr.AsFloat64(idx) })
+	return res, withPath(a.path, err)
+}
+
+func (a *ObservableArr) OptFloat64(idx int, fallback float64) float64 {
+	v, err := a.AsFloat64(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *ObservableArr) PutFloat64(idx int, value float64) Arr {
+	a.put(idx, func(arr *Array) { arr.PutFloat64(idx, value) })
+	return a
+}
+
+func (a *ObservableArr) AddFloat64(value float64) Arr {
+	a.add(func(arr *Array) { arr.AddFloat64(value) })
+	return a
+}
+
+func (a *ObservableArr) AsString(idx int) (string, error) {
+	var res string
+	var err error
+	a.read(func(arr *Array) { res, err = arr.AsString(idx) })
+	return res, withPath(a.path, err)
+}
+
+func (a *ObservableArr) OptString(idx int, fallback string) string {
+	v, err := a.AsString(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *ObservableArr) PutString(idx int, value string) Arr {
+	a.put(idx, func(arr *Array) { arr.PutString(idx, value) })
+	return a
+}
+
+func (a *ObservableArr) AddString(value string) Arr {
+	a.add(func(arr *Array) { arr.AddString(value) })
+	return a
+}
+
+func (a *ObservableArr) AsTime(idx int) (time.Time, error) {
+	var res time.Time
+	var err error
+	a.read(func(arr *Array) { res, err = arr.AsTime(idx) })
+	return res, withPath(a.path, err)
+}
+
+func (a *ObservableArr) OptTime(idx int, fallback time.Time) time.Time {
+	v, err := a.AsTime(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *ObservableArr) PutTime(idx int, value time.Time) Arr {
+	a.put(idx, func(arr *Array) { arr.PutTime(idx, value) })
+	return a
+}
+
+func (a *ObservableArr) AddTime(value time.Time) Arr {
+	a.add(func(arr *Array) { arr.AddTime(value) })
+	return a
+}
+
+func (a *ObservableArr) AsDuration(idx int) (time.Duration, error) {
+	var res time.Duration
+	var err error
+	a.read(func(arr *Array) { res, err = arr.AsDuration(idx) })
+	return res, withPath(a.path, err)
+}
+
+func (a *ObservableArr) OptDuration(idx int, fallback time.Duration) time.Duration {
+	v, err := a.AsDuration(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *ObservableArr) PutDuration(idx int, value time.Duration) Arr {
+	a.put(idx, func(arr *Array) { arr.PutDuration(idx, value) })
+	return a
+}
+
+func (a *ObservableArr) AddDuration(value time.Duration) Arr {
+	a.add(func(arr *Array) { arr.AddDuration(value) })
+	return a
+}
+
+func (a *ObservableArr) AsBytes(idx int) ([]byte, error) {
+	var res []byte
+	var err error
+	a.read(func(arr *Array) {
+		res, err = arr.AsBytes(idx)
+	})
+	return res, withPath(a.path, err)
+}
+
+func (a *ObservableArr) OptBytes(idx int, fallback []byte) []byte {
+	v, err := a.AsBytes(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *ObservableArr) PutBytes(idx int, value []byte) Arr {
+	a.put(idx, func(arr *Array) { arr.PutBytes(idx, value) })
+	return a
+}
+
+func (a *ObservableArr) AddBytes(value []byte) Arr {
+	a.add(func(arr *Array) { arr.AddBytes(value) })
+	return a
+}
+
+func (a *ObservableArr) AsObject(idx int) (Obj, error) {
+	var err error
+	a.read(func(arr *Array) { _, err = arr.AsObject(idx) })
+	if err != nil {
+		return nil, withPath(a.path, err)
+	}
+	return &ObservableObj{root: a.root, path: a.path.Child(idx)}, nil
+}
+
+func (a *ObservableArr) OptObject(idx int) Obj {
+	if v, err := a.AsObject(idx); err == nil {
+		return v
+	}
+	a.put(idx, func(arr *Array) { arr.Put(idx, Object{}) })
+	return &ObservableObj{root: a.root, path: a.path.Child(idx)}
+}
+
+func (a *ObservableArr) PutObject(idx int, value Obj) Arr {
+	v := syncValue(value)
+	a.put(idx, func(arr *Array) { arr.Put(idx, v) })
+	return a
+}
+
+func (a *ObservableArr) AddObject(value Obj) Arr {
+	v := syncValue(value)
+	a.add(func(arr *Array) { *arr = append(*arr, v) })
+	return a
+}
+
+func (a *ObservableArr) AsArray(idx int) (Arr, error) {
+	var err error
+	a.read(func(arr *Array) { _, err = arr.AsArray(idx) })
+	if err != nil {
+		return nil, withPath(a.path, err)
+	}
+	return &ObservableArr{root: a.root, path: a.path.Child(idx)}, nil
+}
+
+func (a *ObservableArr) OptArray(idx int) Arr {
+	if v, err := a.AsArray(idx); err == nil {
+		return v
+	}
+	a.put(idx, func(arr *Array) { arr.Put(idx, &Array{}) })
+	return &ObservableArr{root: a.root, path: a.path.Child(idx)}
+}
+
+func (a *ObservableArr) PutArray(idx int, value Arr) Arr {
+	v := syncValue(value)
+	a.put(idx, func(arr *Array) { arr.Put(idx, v) })
+	return a
+}
+
+func (a *ObservableArr) AddArray(value Arr) Arr {
+	v := syncValue(value)
+	a.add(func(arr *Array) { *arr = append(*arr, v) })
+	return a
+}
+
+func (a *ObservableArr) String() string {
+	var res string
+	a.read(func(arr *Array) { res = arr.String() })
+	return res
+}
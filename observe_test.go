@@ -0,0 +1,85 @@
+package xobj
+
+import (
+	"testing"
+)
+
+func TestObservableObj(t *testing.T) {
+	obj := NewObservableObj(NewObj().PutObject("db", NewObj().PutString("host", "localhost")))
+
+	var all, hosts []string
+	if _, err := obj.Subscribe("", ChangeListenerFunc(func(events ChangeList) {
+		for i := 0; i < events.Size(); i++ {
+			all = append(all, events.Get(i).String())
+		}
+	})); err != nil {
+		t.Fatal(err)
+	}
+	db := obj.OptObject("db")
+	sub, err := db.(*ObservableObj).Subscribe("host", ChangeListenerFunc(func(events ChangeList) {
+		for i := 0; i < events.Size(); i++ {
+			hosts = append(hosts, events.Get(i).PathString())
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.PutString("host", "remote")
+	db.PutString("host", "remote") // unchanged, no event
+	db.PutInt64("port", 80)
+	obj.OptArray("list").AddString("a").PutString(0, "b").Remove(0)
+	obj.PutObject("db", NewObj())
+	sub.Cancel()
+	obj.Remove("db")
+
+	expected := []string{
+		`replace db.host: localhost -> remote`,
+		`add db.port:  -> 80`,
+		`add list:  -> []`,
+		`add list[0]:  -> a`,
+		`replace list[0]: a -> b`,
+		`remove list[0]: b -> `,
+		`replace db: {"host":"remote","port":80} -> {}`,
+		`remove db: {} -> `,
+	}
+	if len(all) != len(expected) {
+		t.Fatal("unexpected", all)
+	}
+	for i := range expected {
+		if all[i] != expected[i] {
+			t.Fatal("unexpected", i, all[i])
+		}
+	}
+
+	if len(hosts) != 2 || hosts[0] != "db.host" || hosts[1] != "db" {
+		t.Fatal("unexpected", hosts)
+	}
+}
+
+func TestObservableObj_Batch(t *testing.T) {
+	obj := NewObservableObj(nil)
+	var batches []int
+	obj.Subscribe("", ChangeListenerFunc(func(events ChangeList) {
+		batches = append(batches, events.Size())
+		// listeners may cause further changes
+		if !obj.Has("derived") {
+			obj.PutBool("derived", true)
+		}
+	}))
+
+	obj.Batch(func() {
+		obj.PutString("a", "1")
+		obj.Batch(func() {
+			obj.PutString("b", "2")
+		})
+		if len(batches) != 0 {
+			t.Fatal("notified within transaction")
+		}
+		obj.PutString("c", "3")
+	})
+
+	if len(batches) != 2 || batches[0] != 3 || batches[1] != 1 {
+		t.Fatal("unexpected", batches)
+	}
+}
@@ -0,0 +1,235 @@
+package xobj
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A Path addresses a nested value, starting at a root Obj. Each segment is either a string, which is
+// a key of an Obj, or an int, which is an index of an Arr.
+type Path []interface{}
+
+// ParsePath reads the notation which is also returned by #Path.String(), like a.b[0].c. Keys which
+// contain special characters are quoted in brackets, e.g. a["b.c"].
+func ParsePath(str string) (Path, error) {
+	res := Path{}
+	i := 0
+	expectKey := true
+	for i < len(str) {
+		switch str[i] {
+		case '.':
+			if expectKey {
+				return nil, fmt.Errorf("unexpected '.' at %d in path '%s'", i, str)
+			}
+			expectKey = true
+			i++
+		case '[':
+			end, seg, err := parseBracket(str, i)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, seg)
+			expectKey = false
+			i = end
+		default:
+			if !expectKey {
+				return nil, fmt.Errorf("expected '.' or '[' at %d in path '%s'", i, str)
+			}
+			end := i
+			for end < len(str) && str[end] != '.' && str[end] != '[' {
+				end++
+			}
+			res = append(res, str[i:end])
+			expectKey = false
+			i = end
+		}
+	}
+	if expectKey && len(str) > 0 {
+		return nil, fmt.Errorf("path '%s' must not end with '.'", str)
+	}
+	return res, nil
+}
+
+// parseBracket parses either [123] or ["quoted key"] beginning at offset
+func parseBracket(str string, offset int) (int, interface{}, error) {
+	closing := strings.IndexByte(str[offset:], ']')
+	if closing < 0 {
+		return 0, nil, fmt.Errorf("missing ']' in path '%s'", str)
+	}
+
+	if offset+1 < len(str) && str[offset+1] == '"' {
+		// find the closing quote, which is not escaped
+		for end := offset + 2; end < len(str); end++ {
+			if str[end] == '\\' {
+				end++
+				continue
+			}
+			if str[end] == '"' {
+				if end+1 >= len(str) || str[end+1] != ']' {
+					return 0, nil, fmt.Errorf("missing ']' at %d in path '%s'", end+1, str)
+				}
+				key, err := strconv.Unquote(str[offset+1 : end+1])
+				if err != nil {
+					return 0, nil, fmt.Errorf("invalid key in path '%s': %v", str, err)
+				}
+				return end + 2, key, nil
+			}
+		}
+		return 0, nil, fmt.Errorf("missing '\"' in path '%s'", str)
+	}
+
+	idx, err := strconv.Atoi(str[offset+1 : offset+closing])
+	if err != nil || idx < 0 {
+		return 0, nil, fmt.Errorf("invalid index '%s' in path '%s'", str[offset+1:offset+closing], str)
+	}
+	return offset + closing + 1, idx, nil
+}
+
+// String returns the dotted notation, e.g. a.b[0].c
+func (p Path) String() string {
+	sb := &strings.Builder{}
+	for i, seg := range p {
+		switch t := seg.(type) {
+		case int:
+			sb.WriteString("[")
+			sb.WriteString(strconv.Itoa(t))
+			sb.WriteString("]")
+		case string:
+			if t == "" || strings.ContainsAny(t, ".[]\"\\") {
+				sb.WriteString("[")
+				sb.WriteString(strconv.Quote(t))
+				sb.WriteString("]")
+				continue
+			}
+			if i > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(t)
+		default:
+			sb.WriteString(fmt.Sprintf("[%v]", t))
+		}
+	}
+	return sb.String()
+}
+
+// Pointer returns the JSON Pointer notation (RFC 6901), e.g. /a/b/0/c
+func (p Path) Pointer() string {
+	sb := &strings.Builder{}
+	for _, seg := range p {
+		sb.WriteString("/")
+		sb.WriteString(pointerEscaper.Replace(fmt.Sprintf("%v", seg)))
+	}
+	return sb.String()
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// LookupPointer returns the value for a JSON Pointer (RFC 6901), e.g. /a/b/0/c. Numeric tokens are indices,
+// if the value is an array. The empty pointer returns the object itself.
+func LookupPointer(obj Obj, pointer string) (interface{}, error) {
+	if pointer == "" {
+		return obj, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid pointer '%s'", pointer)
+	}
+
+	var path Path
+	var cur interface{} = obj
+	for _, token := range strings.Split(pointer[1:], "/") {
+		var seg interface{} = pointerUnescaper.Replace(token)
+		if _, ok := toObj(cur); !ok {
+			idx, err := strconv.Atoi(token)
+			if err != nil {
+				return nil, withPath(path, typeMismatch(cur, "object"))
+			}
+			seg = idx
+		}
+		v, err := child(cur, seg)
+		if err != nil {
+			return nil, withPath(path, err)
+		}
+		path = append(path, seg)
+		cur = v
+	}
+	return cur, nil
+}
+
+// Child returns a new path which has the given segment appended
+func (p Path) Child(seg interface{}) Path {
+	res := make(Path, len(p), len(p)+1)
+	copy(res, p)
+	return append(res, seg)
+}
+
+// Lookup follows the path beginning at the given object and returns the value. Any returned error is
+// an *Error which contains the path up to the segment which has failed.
+func Lookup(obj Obj, path Path) (interface{}, error) {
+	var cur interface{} = obj
+	for i, seg := range path {
+		v, err := child(cur, seg)
+		if err != nil {
+			return nil, withPath(path[:i], err)
+		}
+		cur = v
+	}
+	return cur, nil
+}
+
+// child returns the value of the segment from the given container, which may be wrapped or not
+func child(container interface{}, seg interface{}) (interface{}, error) {
+	switch t := seg.(type) {
+	case string:
+		obj, ok := toObj(container)
+		if !ok {
+			return nil, typeMismatch(container, "object")
+		}
+		if !obj.Has(t) {
+			return nil, unknownFieldName(t)
+		}
+		return obj.Get(t), nil
+	case int:
+		arr, ok := toArr(container)
+		if !ok {
+			return nil, typeMismatch(container, "array")
+		}
+		if t < 0 || t >= arr.Size() {
+			return nil, outOfBoundsAt(t, arr.Size())
+		}
+		return arr.Get(t), nil
+	}
+	return nil, fmt.Errorf("invalid path segment type %T", seg)
+}
+
+// toObj wraps a raw map or returns the Obj as is
+func toObj(v interface{}) (Obj, bool) {
+	switch t := v.(type) {
+	case Obj:
+		return t, true
+	case map[string]interface{}:
+		return Object(t), true
+	}
+	return nil, false
+}
+
+// toArr wraps raw slices, the slice pointers and wrappers or returns the Arr as is. In contrast
+// to #AsArray() the parent is not modified, so appending to the result of a raw slice is not
+// visible to the parent.
+func toArr(v interface{}) (Arr, bool) {
+	switch t := v.(type) {
+	case Arr:
+		return t, true
+	case *[]interface{}:
+		return (*Array)(t), true
+	case []interface{}:
+		tmp := Array(t)
+		return &tmp, true
+	case wrapper:
+		tmp := Array(t.Unwrap())
+		return &tmp, true
+	}
+	return nil, false
+}
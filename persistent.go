@@ -0,0 +1,993 @@
+package xobj
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// This file contains persistent (immutable) implementations of Obj and Arr. Objects are backed by a hash array
+// mapped trie (HAMT) and arrays by a bit partitioned vector trie, so that each modification only copies the
+// path to the changed value and shares everything else with the previous version.
+
+const (
+	trieBits  = 5
+	trieWidth = 1 << trieBits
+	trieMask  = trieWidth - 1
+)
+
+// hamtNode is either a bitmap indexed node or, below the maximum depth, a collision node with a flat list
+type hamtNode struct {
+	bitmap    uint32
+	entries   []hamtEntry
+	collision bool
+}
+
+// hamtEntry is either a leaf with key and value or a reference to a child node
+type hamtEntry struct {
+	hash  uint32
+	key   string
+	value interface{}
+	child *hamtNode
+}
+
+// hashKey is the 32 bit FNV-1a hash
+func hashKey(key string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return h
+}
+
+func bitpos(hash uint32, shift uint) uint32 {
+	return 1 << ((hash >> shift) & trieMask)
+}
+
+func popcount(v uint32) int {
+	n := 0
+	for v != 0 {
+		v &= v - 1
+		n++
+	}
+	return n
+}
+
+func (n *hamtNode) index(bit uint32) int {
+	return popcount(n.bitmap & (bit - 1))
+}
+
+func (n *hamtNode) get(hash uint32, key string, shift uint) (interface{}, bool) {
+	for n != nil {
+		if n.collision {
+			for _, e := range n.entries {
+				if e.key == key {
+					return e.value, true
+				}
+			}
+			return nil, false
+		}
+		bit := bitpos(hash, shift)
+		if n.bitmap&bit == 0 {
+			return nil, false
+		}
+		e := n.entries[n.index(bit)]
+		if e.child == nil {
+			if e.key == key {
+				return e.value, true
+			}
+			return nil, false
+		}
+		n = e.child
+		shift += trieBits
+	}
+	return nil, false
+}
+
+// set returns the new node and true, if a key has been added instead of replaced
+func (n *hamtNode) set(hash uint32, key string, value interface{}, shift uint) (*hamtNode, bool) {
+	if n.collision {
+		res := &hamtNode{collision: true, entries: append([]hamtEntry(nil), n.entries...)}
+		for i, e := range res.entries {
+			if e.key == key {
+				res.entries[i].value = value
+				return res, false
+			}
+		}
+		res.entries = append(res.entries, hamtEntry{hash: hash, key: key, value: value})
+		return res, true
+	}
+
+	bit := bitpos(hash, shift)
+	idx := n.index(bit)
+	if n.bitmap&bit == 0 {
+		res := &hamtNode{bitmap: n.bitmap | bit, entries: make([]hamtEntry, len(n.entries)+1)}
+		copy(res.entries, n.entries[:idx])
+		res.entries[idx] = hamtEntry{hash: hash, key: key, value: value}
+		copy(res.entries[idx+1:], n.entries[idx:])
+		return res, true
+	}
+
+	res := &hamtNode{bitmap: n.bitmap, entries: append([]hamtEntry(nil), n.entries...)}
+	e := n.entries[idx]
+	switch {
+	case e.child != nil:
+		child, added := e.child.set(hash, key, value, shift+trieBits)
+		res.entries[idx] = hamtEntry{child: child}
+		return res, added
+	case e.key == key:
+		res.entries[idx].value = value
+		return res, false
+	default:
+		res.entries[idx] = hamtEntry{child: mergeLeaves(e, hamtEntry{hash: hash, key: key, value: value}, shift+trieBits)}
+		return res, true
+	}
+}
+
+// mergeLeaves creates a sub trie for two leaves with different keys
+func mergeLeaves(a, b hamtEntry, shift uint) *hamtNode {
+	if shift >= 32 {
+		return &hamtNode{collision: true, entries: []hamtEntry{a, b}}
+	}
+	ba, bb := bitpos(a.hash, shift), bitpos(b.hash, shift)
+	if ba == bb {
+		return &hamtNode{bitmap: ba, entries: []hamtEntry{{child: mergeLeaves(a, b, shift+trieBits)}}}
+	}
+	if ba < bb {
+		return &hamtNode{bitmap: ba | bb, entries: []hamtEntry{a, b}}
+	}
+	return &hamtNode{bitmap: ba | bb, entries: []hamtEntry{b, a}}
+}
+
+// remove returns the new node, which is nil if it became empty, and true if the key existed
+func (n *hamtNode) remove(hash uint32, key string, shift uint) (*hamtNode, bool) {
+	if n.collision {
+		for i, e := range n.entries {
+			if e.key == key {
+				if len(n.entries) == 1 {
+					return nil, true
+				}
+				res := &hamtNode{collision: true, entries: make([]hamtEntry, 0, len(n.entries)-1)}
+				res.entries = append(append(res.entries, n.entries[:i]...), n.entries[i+1:]...)
+				return res, true
+			}
+		}
+		return n, false
+	}
+
+	bit := bitpos(hash, shift)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	idx := n.index(bit)
+	e := n.entries[idx]
+	if e.child != nil {
+		child, removed := e.child.remove(hash, key, shift+trieBits)
+		if !removed {
+			return n, false
+		}
+		if child != nil {
+			res := &hamtNode{bitmap: n.bitmap, entries: append([]hamtEntry(nil), n.entries...)}
+			res.entries[idx] = hamtEntry{child: child}
+			return res, true
+		}
+	} else if e.key != key {
+		return n, false
+	}
+
+	if len(n.entries) == 1 {
+		return nil, true
+	}
+	res := &hamtNode{bitmap: n.bitmap &^ bit, entries: make([]hamtEntry, 0, len(n.entries)-1)}
+	res.entries = append(append(res.entries, n.entries[:idx]...), n.entries[idx+1:]...)
+	return res, true
+}
+
+func (n *hamtNode) each(fn func(key string, value interface{})) {
+	if n == nil {
+		return
+	}
+	for _, e := range n.entries {
+		if e.child != nil {
+			e.child.each(fn)
+			continue
+		}
+		fn(e.key, e.value)
+	}
+}
+
+// sameValue compares primitives and persistent containers without panicking on incomparable types
+func sameValue(a, b interface{}) bool {
+	switch a.(type) {
+	case nil, string, bool, float64, int64, *PersistentObj, *PersistentArr:
+		return a == b
+	}
+	return false
+}
+
+// isPersistent returns true for the containers, whose modifications return a new version
+func isPersistent(v interface{}) bool {
+	switch v.(type) {
+	case *PersistentObj, *PersistentArr:
+		return true
+	}
+	return false
+}
+
+// immutable is returned by the helpers which modify containers in place
+func immutable(v interface{}) error {
+	return fmt.Errorf("cannot modify %T in place, use #PersistentObj.SetIn() instead", v)
+}
+
+// persistentValue converts containers into persistent containers, which are used as is
+func persistentValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case *PersistentObj, *PersistentArr:
+		return v
+	case []byte:
+		return copyBytes(t)
+	case int:
+		return int64(t)
+	}
+	if obj, ok := toObj(v); ok {
+		return ToPersistentObj(obj)
+	}
+	if arr, ok := toArr(v); ok {
+		return ToPersistentArr(arr)
+	}
+	return v
+}
+
+//=
+
+var _ Obj = (*PersistentObj)(nil)
+
+// A PersistentObj is an immutable Obj. Every modification returns a new version and leaves the receiver
+// untouched, so the result of Put and friends must always be used. Unchanged versions are returned as
+// is, so that versions can be compared cheaply by pointer. Nested containers are persistent as well and
+// modifying them does not change the parent, use #SetIn() for that. OptObject and OptArray cannot
+// insert anything and just return empty containers for missing fields. The zero value is not usable,
+// use #NewPersistentObj().
+type PersistentObj struct {
+	root *hamtNode
+	size int
+}
+
+var emptyPersistentObj = &PersistentObj{root: &hamtNode{}}
+
+// NewPersistentObj returns the empty persistent object.
+func NewPersistentObj() *PersistentObj {
+	return emptyPersistentObj
+}
+
+// ToPersistentObj creates a persistent deep copy of any Obj.
+func ToPersistentObj(obj Obj) *PersistentObj {
+	if p, ok := obj.(*PersistentObj); ok {
+		return p
+	}
+	res := NewPersistentObj()
+	keys := obj.Keys()
+	for i := 0; i < keys.Size(); i++ {
+		k := keys.Get(i)
+		res = res.with(k, persistentValue(obj.Get(k)))
+	}
+	return res
+}
+
+func (o *PersistentObj) with(name string, value interface{}) *PersistentObj {
+	if old, ok := o.root.get(hashKey(name), name, 0); ok && sameValue(old, value) {
+		return o
+	}
+	root, added := o.root.set(hashKey(name), name, value, 0)
+	size := o.size
+	if added {
+		size++
+	}
+	return &PersistentObj{root: root, size: size}
+}
+
+func (o *PersistentObj) value(name string) (interface{}, bool) {
+	return o.root.get(hashKey(name), name, 0)
+}
+
+// Size returns the amount of keys
+func (o *PersistentObj) Size() int {
+	return o.size
+}
+
+// SetIn returns a new version with the value at the given path replaced. Missing or null intermediate
+// values are created as objects or arrays, depending on the type of the next segment. Indices must be
+// within the bounds or equal to the size, to append.
+func (o *PersistentObj) SetIn(path Path, value interface{}) (*PersistentObj, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot replace the root object")
+	}
+	v, err := setIn(o, path, persistentValue(value))
+	if err != nil {
+		return nil, err
+	}
+	return v.(*PersistentObj), nil
+}
+
+func setIn(container interface{}, path Path, value interface{}) (interface{}, error) {
+	seg := path[0]
+	switch c := container.(type) {
+	case *PersistentObj:
+		key, ok := seg.(string)
+		if !ok {
+			return nil, typeMismatch(c, "array")
+		}
+		if len(path) == 1 {
+			return c.with(key, value), nil
+		}
+		next, _ := c.value(key)
+		if next == nil {
+			next = emptyContainer(path[1])
+		}
+		v, err := setIn(next, path[1:], value)
+		if err != nil {
+			return nil, withPath(Path{key}, err)
+		}
+		return c.with(key, v), nil
+	case *PersistentArr:
+		idx, ok := seg.(int)
+		if !ok {
+			return nil, typeMismatch(c, "object")
+		}
+		if idx < 0 || idx > c.size {
+			return nil, outOfBoundsAt(idx, c.size)
+		}
+		var next interface{}
+		if idx < c.size {
+			next = c.get(idx)
+		}
+		if len(path) > 1 {
+			if next == nil {
+				next = emptyContainer(path[1])
+			}
+			v, err := setIn(next, path[1:], value)
+			if err != nil {
+				return nil, withPath(Path{idx}, err)
+			}
+			value = v
+		}
+		if idx == c.size {
+			return c.push(value), nil
+		}
+		return c.set(idx, value), nil
+	}
+	return nil, typeMismatch(container, "object")
+}
+
+func emptyContainer(seg interface{}) interface{} {
+	if _, ok := seg.(int); ok {
+		return NewPersistentArr()
+	}
+	return NewPersistentObj()
+}
+
+func (o *PersistentObj) Keys() StrList {
+	res := make(StringList, 0, o.size)
+	o.root.each(func(key string, value interface{}) {
+		res = append(res, key)
+	})
+	return res
+}
+
+func (o *PersistentObj) Get(name string) interface{} {
+	v, _ := o.value(name)
+	return v
+}
+
+func (o *PersistentObj) Put(name string, value interface{}) Obj {
+	return o.with(name, persistentValue(value))
+}
+
+func (o *PersistentObj) Remove(name string) Obj {
+	root, removed := o.root.remove(hashKey(name), name, 0)
+	if !removed {
+		return o
+	}
+	if root == nil {
+		return emptyPersistentObj
+	}
+	return &PersistentObj{root: root, size: o.size - 1}
+}
+
+func (o *PersistentObj) Has(name string) bool {
+	_, ok := o.value(name)
+	return ok
+}
+
+func (o *PersistentObj) IsNull(name string) bool {
+	return o.Get(name) == nil
+}
+
+func (o *PersistentObj) AsInt64(name string) (int64, error) {
+	v, ok := o.value(name)
+	if !ok {
+		return 0, unknownFieldName(name)
+	}
+	res, err := asInt64(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *PersistentObj) OptInt64(name string, fallback int64) int64 {
+	v, err := o.AsInt64(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *PersistentObj) PutInt64(name string, value int64) Obj {
+	return o.with(name, value)
+}
+
+func (o *PersistentObj) AsBool(name string) (bool, error) {
+	v, ok := o.value(name)
+	if !ok {
+		return false, unknownFieldName(name)
+	}
+	res, err := asBool(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *PersistentObj) OptBool(name string, fallback bool) bool {
+	v, err := o.AsBool(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *PersistentObj) PutBool(name string, value bool) Obj {
+	return o.with(name, value)
+}
+
+func (o *PersistentObj) AsFloat64(name string) (float64, error) {
+	v, ok := o.value(name)
+	if !ok {
+		return 0, unknownFieldName(name)
+	}
+	res, err := asFloat64(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *PersistentObj) OptFloat64(name string, fallback float64) float64 {
+	v, err := o.AsFloat64(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *PersistentObj) PutFloat64(name string, value float64) Obj {
+	return o.with(name, value)
+}
+
+func (o *PersistentObj) AsString(name string) (string, error) {
+	v, ok := o.value(name)
+	if !ok {
+		return "", unknownFieldName(name)
+	}
+	res, err := asString(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *PersistentObj) OptString(name string, fallback string) string {
+	v, err := o.AsString(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *PersistentObj) PutString(name string, value string) Obj {
+	return o.with(name, value)
+}
+
+func (o *PersistentObj) AsTime(name string) (time.Time, error) {
+	v, ok := o.value(name)
+	if !ok {
+		return time.Time{}, unknownFieldName(name)
+	}
+	res, err := asTime(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *PersistentObj) OptTime(name string, fallback time.Time) time.Time {
+	v, err := o.AsTime(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *PersistentObj) PutTime(name string, value time.Time) Obj {
+	return o.with(name, formatTime(value))
+}
+
+func (o *PersistentObj) AsDuration(name string) (time.Duration, error) {
+	v, ok := o.value(name)
+	if !ok {
+		return 0, unknownFieldName(name)
+	}
+	res, err := asDuration(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *PersistentObj) OptDuration(name string, fallback time.Duration) time.Duration {
+	v, err := o.AsDuration(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *PersistentObj) PutDuration(name string, value time.Duration) Obj {
+	return o.with(name, formatDuration(value))
+}
+
+func (o *PersistentObj) AsBytes(name string) ([]byte, error) {
+	v, ok := o.value(name)
+	if !ok {
+		return nil, unknownFieldName(name)
+	}
+	res, err := asBytes(v)
+	return res, withPath(Path{name}, err)
+}
+
+func (o *PersistentObj) OptBytes(name string, fallback []byte) []byte {
+	v, err := o.AsBytes(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *PersistentObj) PutBytes(name string, value []byte) Obj {
+	return o.with(name, copyBytes(value))
+}
+
+func (o *PersistentObj) AsObject(name string) (Obj, error) {
+	v, ok := o.value(name)
+	if !ok {
+		return nil, unknownFieldName(name)
+	}
+	if obj, ok := v.(*PersistentObj); ok {
+		return obj, nil
+	}
+	return nil, withPath(Path{name}, typeMismatch(v, "object"))
+}
+
+func (o *PersistentObj) OptObject(name string) Obj {
+	v, err := o.AsObject(name)
+	if err != nil {
+		return NewPersistentObj()
+	}
+	return v
+}
+
+func (o *PersistentObj) PutObject(name string, value Obj) Obj {
+	return o.with(name, persistentValue(value))
+}
+
+func (o *PersistentObj) AsArray(name string) (Arr, error) {
+	v, ok := o.value(name)
+	if !ok {
+		return nil, unknownFieldName(name)
+	}
+	if arr, ok := v.(*PersistentArr); ok {
+		return arr, nil
+	}
+	return nil, withPath(Path{name}, typeMismatch(v, "array"))
+}
+
+func (o *PersistentObj) OptArray(name string) Arr {
+	v, err := o.AsArray(name)
+	if err != nil {
+		return NewPersistentArr()
+	}
+	return v
+}
+
+func (o *PersistentObj) PutArray(name string, value Arr) Obj {
+	return o.with(name, persistentValue(value))
+}
+
+// MarshalJSON serializes like #String() does
+func (o *PersistentObj) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	_, err := WriteTo(buf, o, DefaultEncodeOptions())
+	return buf.Bytes(), err
+}
+
+func (o *PersistentObj) String() string {
+	data, err := o.MarshalJSON()
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+//==
+
+// vecNode is a node of the vector trie, whose items are either *vecNode or values on the leaf level
+type vecNode struct {
+	items [trieWidth]interface{}
+}
+
+var _ Arr = (*PersistentArr)(nil)
+
+// A PersistentArr is an immutable Arr, see also PersistentObj. Put, Add and removing the last element
+// are logarithmic, removing any other element copies the array.
+type PersistentArr struct {
+	size  int
+	shift uint
+	root  *vecNode
+}
+
+var emptyPersistentArr = &PersistentArr{root: &vecNode{}}
+
+// NewPersistentArr returns the empty persistent array.
+func NewPersistentArr() *PersistentArr {
+	return emptyPersistentArr
+}
+
+// ToPersistentArr creates a persistent deep copy of any Arr.
+func ToPersistentArr(arr Arr) *PersistentArr {
+	if p, ok := arr.(*PersistentArr); ok {
+		return p
+	}
+	res := NewPersistentArr()
+	for i := 0; i < arr.Size(); i++ {
+		res = res.push(persistentValue(arr.Get(i)))
+	}
+	return res
+}
+
+func (a *PersistentArr) get(idx int) interface{} {
+	n := a.root
+	for level := a.shift; level > 0; level -= trieBits {
+		n = n.items[(idx>>level)&trieMask].(*vecNode)
+	}
+	return n.items[idx&trieMask]
+}
+
+func (a *PersistentArr) set(idx int, value interface{}) *PersistentArr {
+	if sameValue(a.get(idx), value) {
+		return a
+	}
+	return &PersistentArr{size: a.size, shift: a.shift, root: vecSet(a.root, a.shift, idx, value)}
+}
+
+func vecSet(n *vecNode, level uint, idx int, value interface{}) *vecNode {
+	res := &vecNode{}
+	if n != nil {
+		*res = *n
+	}
+	if level == 0 {
+		res.items[idx&trieMask] = value
+		return res
+	}
+	slot := (idx >> level) & trieMask
+	child, _ := res.items[slot].(*vecNode)
+	res.items[slot] = vecSet(child, level-trieBits, idx, value)
+	return res
+}
+
+func (a *PersistentArr) push(value interface{}) *PersistentArr {
+	root, shift := a.root, a.shift
+	if a.size == 1<<(shift+trieBits) {
+		// the trie is full, so it grows by one level
+		root = &vecNode{}
+		root.items[0] = a.root
+		shift += trieBits
+	}
+	return &PersistentArr{size: a.size + 1, shift: shift, root: vecSet(root, shift, a.size, value)}
+}
+
+func (a *PersistentArr) checkBounds(idx int) {
+	if idx < 0 || idx >= a.size {
+		panic(outOfBoundsAt(idx, a.size))
+	}
+}
+
+func (a *PersistentArr) Size() int {
+	return a.size
+}
+
+func (a *PersistentArr) Get(idx int) interface{} {
+	a.checkBounds(idx)
+	return a.get(idx)
+}
+
+func (a *PersistentArr) Put(idx int, value interface{}) Arr {
+	a.checkBounds(idx)
+	return a.set(idx, persistentValue(value))
+}
+
+func (a *PersistentArr) IsNull(idx int) bool {
+	if idx < 0 || idx >= a.size {
+		return true
+	}
+	return a.get(idx) == nil
+}
+
+func (a *PersistentArr) Remove(idx int) Arr {
+	a.checkBounds(idx)
+	if a.size == 1 {
+		return emptyPersistentArr
+	}
+	if idx == a.size-1 {
+		// clear the slot, so that the value can be collected
+		res := a.set(idx, nil)
+		if res == a {
+			res = &PersistentArr{shift: a.shift, root: a.root}
+		}
+		res.size = a.size - 1
+		return res
+	}
+	res := NewPersistentArr()
+	for i := 0; i < a.size; i++ {
+		if i != idx {
+			res = res.push(a.get(i))
+		}
+	}
+	return res
+}
+
+func (a *PersistentArr) element(idx int) (interface{}, error) {
+	if idx < 0 || idx >= a.size {
+		return nil, outOfBoundsAt(idx, a.size)
+	}
+	return a.get(idx), nil
+}
+
+func (a *PersistentArr) AsInt64(idx int) (int64, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := asInt64(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *PersistentArr) OptInt64(idx int, fallback int64) int64 {
+	v, err := a.AsInt64(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *PersistentArr) PutInt64(idx int, value int64) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *PersistentArr) AddInt64(value int64) Arr {
+	return a.push(value)
+}
+
+func (a *PersistentArr) AsBool(idx int) (bool, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return false, err
+	}
+	res, err := asBool(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *PersistentArr) OptBool(idx int, fallback bool) bool {
+	v, err := a.AsBool(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *PersistentArr) PutBool(idx int, value bool) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *PersistentArr) AddBool(value bool) Arr {
+	return a.push(value)
+}
+
+func (a *PersistentArr) AsFloat64(idx int) (float64, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := asFloat64(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *PersistentArr) OptFloat64(idx int, fallback float64) float64 {
+	v, err := a.AsFloat64(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *PersistentArr) PutFloat64(idx int, value float64) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *PersistentArr) AddFloat64(value float64) Arr {
+	return a.push(value)
+}
+
+func (a *PersistentArr) AsString(idx int) (string, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return "", err
+	}
+	res, err := asString(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *PersistentArr) OptString(idx int, fallback string) string {
+	v, err := a.AsString(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *PersistentArr) PutString(idx int, value string) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *PersistentArr) AddString(value string) Arr {
+	return a.push(value)
+}
+
+func (a *PersistentArr) AsTime(idx int) (time.Time, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	res, err := asTime(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *PersistentArr) OptTime(idx int, fallback time.Time) time.Time {
+	v, err := a.AsTime(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *PersistentArr) PutTime(idx int, value time.Time) Arr {
+	return a.Put(idx, formatTime(value))
+}
+
+func (a *PersistentArr) AddTime(value time.Time) Arr {
+	return a.push(formatTime(value))
+}
+
+func (a *PersistentArr) AsDuration(idx int) (time.Duration, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := asDuration(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *PersistentArr) OptDuration(idx int, fallback time.Duration) time.Duration {
+	v, err := a.AsDuration(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *PersistentArr) PutDuration(idx int, value time.Duration) Arr {
+	return a.Put(idx, formatDuration(value))
+}
+
+func (a *PersistentArr) AddDuration(value time.Duration) Arr {
+	return a.push(formatDuration(value))
+}
+
+func (a *PersistentArr) AsBytes(idx int) ([]byte, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := asBytes(v)
+	return res, withPath(Path{idx}, err)
+}
+
+func (a *PersistentArr) OptBytes(idx int, fallback []byte) []byte {
+	v, err := a.AsBytes(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *PersistentArr) PutBytes(idx int, value []byte) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *PersistentArr) AddBytes(value []byte) Arr {
+	return a.push(copyBytes(value))
+}
+
+func (a *PersistentArr) AsObject(idx int) (Obj, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return nil, err
+	}
+	if obj, ok := v.(*PersistentObj); ok {
+		return obj, nil
+	}
+	return nil, withPath(Path{idx}, typeMismatch(v, "object"))
+}
+
+func (a *PersistentArr) OptObject(idx int) Obj {
+	a.checkBounds(idx)
+	v, err := a.AsObject(idx)
+	if err != nil {
+		return NewPersistentObj()
+	}
+	return v
+}
+
+func (a *PersistentArr) PutObject(idx int, value Obj) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *PersistentArr) AddObject(value Obj) Arr {
+	return a.push(persistentValue(value))
+}
+
+func (a *PersistentArr) AsArray(idx int) (Arr, error) {
+	v, err := a.element(idx)
+	if err != nil {
+		return nil, err
+	}
+	if arr, ok := v.(*PersistentArr); ok {
+		return arr, nil
+	}
+	return nil, withPath(Path{idx}, typeMismatch(v, "array"))
+}
+
+func (a *PersistentArr) OptArray(idx int) Arr {
+	a.checkBounds(idx)
+	v, err := a.AsArray(idx)
+	if err != nil {
+		return NewPersistentArr()
+	}
+	return v
+}
+
+func (a *PersistentArr) PutArray(idx int, value Arr) Arr {
+	return a.Put(idx, value)
+}
+
+func (a *PersistentArr) AddArray(value Arr) Arr {
+	return a.push(persistentValue(value))
+}
+
+// MarshalJSON serializes like #String() does
+func (a *PersistentArr) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	_, err := WriteArrTo(buf, a, DefaultEncodeOptions())
+	return buf.Bytes(), err
+}
+
+func (a *PersistentArr) String() string {
+	data, err := a.MarshalJSON()
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
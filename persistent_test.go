@@ -0,0 +1,130 @@
+package xobj
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestPersistentObj(t *testing.T) {
+	v0 := NewPersistentObj()
+	v1 := v0.PutString("name", "a")
+	v2 := v1.PutString("name", "b").PutInt64("age", 3)
+
+	if v0.Size() != 0 || v1.OptString("name", "") != "a" || v2.OptString("name", "") != "b" {
+		t.Fatal("unexpected", v0, v1, v2)
+	}
+	if v2.PutInt64("age", 3) != v2 || v2.Remove("missing") != v2 {
+		t.Fatal("unchanged versions must be identical")
+	}
+	if v3 := v2.Remove("name"); v3.Has("name") || !v2.Has("name") || v3.Keys().Size() != 1 {
+		t.Fatal("unexpected", v3, v2)
+	}
+
+	// enough keys to cause deep tries and removals down to the empty object
+	var obj Obj = v0
+	for i := 0; i < 5000; i++ {
+		obj = obj.PutInt64(strconv.Itoa(i), int64(i))
+	}
+	if obj.Keys().Size() != 5000 || obj.OptInt64("4711", 0) != 4711 {
+		t.Fatal("unexpected", obj.Keys().Size())
+	}
+	for i := 0; i < 5000; i++ {
+		obj = obj.Remove(strconv.Itoa(i))
+	}
+	if obj != v0 {
+		t.Fatal("unexpected", obj)
+	}
+}
+
+func TestPersistentObj_Nested(t *testing.T) {
+	src := NewObj().PutObject("db", NewObj().PutString("host", "localhost"))
+	v1 := ToPersistentObj(src)
+	src.OptObject("db").PutString("host", "changed")
+
+	v2, err := v1.SetIn(Path{"db", "ports", 0}, 8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := v1.OptObject("db").OptString("host", ""); v != "localhost" {
+		t.Fatal("unexpected", v)
+	}
+	if v1.OptObject("db").Has("ports") || v2.String() != `{"db":{"host":"localhost","ports":[8080]}}` {
+		t.Fatal("unexpected", v1, v2)
+	}
+	if _, err := v2.SetIn(Path{"db", "ports", 5}, 1); err == nil {
+		t.Fatal("expected out of bounds")
+	}
+	if !Equal(v2, Object{"db": map[string]interface{}{"host": "localhost", "ports": []interface{}{8080}}}) {
+		t.Fatal("unexpected", v2)
+	}
+}
+
+func TestPersistentArr(t *testing.T) {
+	var arr Arr = NewPersistentArr()
+	versions := []Arr{arr}
+	for i := 0; i < 2000; i++ {
+		arr = arr.AddInt64(int64(i))
+		versions = append(versions, arr)
+	}
+
+	for i, v := range versions {
+		if v.Size() != i {
+			t.Fatal("unexpected", i, v.Size())
+		}
+	}
+	if v := versions[1500].OptInt64(1024, 0); v != 1024 {
+		t.Fatal("unexpected", v)
+	}
+
+	changed := arr.PutString(1024, "x")
+	if arr.OptString(1024, "") != "1024" || changed.OptString(1024, "") != "x" {
+		t.Fatal("unexpected")
+	}
+
+	removed := arr.Remove(0).Remove(arr.Size() - 2)
+	if removed.Size() != 1998 || removed.OptInt64(0, 0) != 1 || removed.OptInt64(1997, 0) != 1998 {
+		t.Fatal("unexpected", removed.Size())
+	}
+	if arr.Size() != 2000 {
+		t.Fatal("unexpected", arr.Size())
+	}
+
+	if NewPersistentArr().AddString("a").Remove(0) != NewPersistentArr() {
+		t.Fatal("expected the empty array")
+	}
+}
+
+func TestPersistentObj_InPlaceHelpers(t *testing.T) {
+	p := NewPersistentObj().Put("a", NewObj().PutString("b", "x")).(*PersistentObj)
+	if err := Set(p, "a.b", "y"); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := Set(p, "c", "y"); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := Transform(p, func(c *Cursor) error { return nil }); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// a persistent child of a mutable document
+	obj := NewObj().Put("p", p)
+	if err := Set(obj, "p.a.b", "y"); err == nil {
+		t.Fatal("expected error")
+	}
+	if p.String() != `{"a":{"b":"x"}}` {
+		t.Fatal("unexpected", p.String())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	_ = Transform(obj, func(c *Cursor) error {
+		if c.Path().String() == "p.a" {
+			c.Replace("z")
+		}
+		return nil
+	})
+}
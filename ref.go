@@ -0,0 +1,452 @@
+package xobj
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// A RefResolver resolves $ref members like {"$ref": "common.json#/definitions/Pet"}, which are used by
+// JSON Schema and OpenAPI. References are relative to the document which contains them, or to the nearest
+// $id. Files are read from a fs.FS, parsed by #Parse() and cached, so each file is loaded only once. Other
+// documents, e.g. with absolute URIs, can be registered by #RefResolver.AddDocument(). A RefResolver is not
+// safe for concurrent use.
+type RefResolver struct {
+	// DefsKey is the member of the root, which receives the external values of a #RefResolver.Bundle().
+	// The default is $defs.
+	DefsKey string
+
+	fsys fs.FS
+	docs map[string]interface{} // documents, $id and $anchor values by uri
+}
+
+// NewRefResolver creates a resolver which loads files from the given file system, which may be nil.
+func NewRefResolver(fsys fs.FS) *RefResolver {
+	return &RefResolver{fsys: fsys, docs: map[string]interface{}{}}
+}
+
+// AddDocument registers a document for the given uri, so that references to it are not loaded from the file
+// system.
+func (r *RefResolver) AddDocument(uri string, obj Obj) {
+	r.register(uri, syncValue(obj))
+}
+
+// Load returns the cached document for the uri or parses the file.
+func (r *RefResolver) Load(uri string) (Obj, error) {
+	v, err := r.load(uri)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := toObj(v)
+	if !ok {
+		return nil, fmt.Errorf("document %s: %w", uri, typeMismatch(v, "object"))
+	}
+	return obj, nil
+}
+
+func (r *RefResolver) load(uri string) (interface{}, error) {
+	if v, ok := r.docs[uri]; ok {
+		return v, nil
+	}
+	if r.fsys == nil || strings.Contains(uri, ":") {
+		return nil, fmt.Errorf("cannot load document %s", uri)
+	}
+	data, err := fs.ReadFile(r.fsys, uri)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", uri, err)
+	}
+	doc := syncValue(obj)
+	r.register(uri, doc)
+	return doc, nil
+}
+
+// register remembers the document and all of its $id and $anchor values
+func (r *RefResolver) register(uri string, doc interface{}) {
+	r.docs[uri] = doc
+	var walk func(base string, v interface{})
+	walk = func(base string, v interface{}) {
+		if obj, ok := toObj(v); ok && !isNil(v) {
+			base = r.base(base, obj)
+			if _, ok := r.docs[base]; !ok {
+				r.docs[base] = v
+			}
+			if anchor, ok := obj.Get("$anchor").(string); ok {
+				r.docs[base+"#"+anchor] = v
+			}
+			for _, k := range sortedKeys(obj) {
+				walk(base, obj.Get(k))
+			}
+			return
+		}
+		if arr, ok := toArr(v); ok && !isNil(v) {
+			for i := 0; i < arr.Size(); i++ {
+				walk(base, arr.Get(i))
+			}
+		}
+	}
+	walk(uri, doc)
+}
+
+// base returns the base uri for the members of the object
+func (r *RefResolver) base(base string, obj Obj) string {
+	if id, ok := obj.Get("$id").(string); ok {
+		if doc, _, err := refTarget(base, id); err == nil {
+			return doc
+		}
+	}
+	return base
+}
+
+// refTarget returns the document uri and the fragment of the reference
+func refTarget(base, ref string) (string, string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid $ref '%s': %w", ref, err)
+	}
+	fragment := u.Fragment
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	switch {
+	case u.String() == "":
+		return base, fragment, nil
+	case u.IsAbs():
+		return u.String(), fragment, nil
+	}
+
+	b, err := url.Parse(base)
+	if err == nil && b.IsAbs() {
+		return b.ResolveReference(u).String(), fragment, nil
+	}
+	if strings.HasPrefix(u.Path, "/") {
+		return strings.TrimPrefix(path.Clean(u.Path), "/"), fragment, nil
+	}
+	return path.Join(path.Dir(base), u.Path), fragment, nil
+}
+
+// target returns the value for the fragment, which is either a JSON Pointer or an $anchor
+func (r *RefResolver) target(doc, fragment string) (interface{}, error) {
+	v, err := r.load(doc)
+	if err != nil {
+		return nil, err
+	}
+	if fragment == "" {
+		return v, nil
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		if v, ok := r.docs[doc+"#"+fragment]; ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("unknown anchor %s#%s", doc, fragment)
+	}
+	obj, ok := toObj(v)
+	if !ok {
+		return nil, fmt.Errorf("document %s: %w", doc, typeMismatch(v, "object"))
+	}
+	res, err := LookupPointer(obj, fragment)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve %s#%s: %w", doc, fragment, err)
+	}
+	return res, nil
+}
+
+// refOf returns the reference of the object, if it is one
+func refOf(v interface{}) (Obj, string, bool) {
+	obj, ok := toObj(v)
+	if !ok || isNil(v) {
+		return nil, "", false
+	}
+	ref, ok := obj.Get("$ref").(string)
+	return obj, ref, ok
+}
+
+// Follow resolves a value lazily: if it is a reference, the referenced value and its base uri are returned,
+// following chained references. Other values are returned as is. The returned value must not be modified.
+func (r *RefResolver) Follow(base string, v interface{}) (interface{}, string, error) {
+	seen := map[string]bool{}
+	for {
+		obj, ref, ok := refOf(v)
+		if !ok {
+			return v, base, nil
+		}
+		doc, fragment, err := refTarget(r.base(base, obj), ref)
+		if err != nil {
+			return nil, "", err
+		}
+		key := doc + "#" + fragment
+		if seen[key] {
+			return nil, "", fmt.Errorf("cyclic $ref %s", key)
+		}
+		seen[key] = true
+		if v, err = r.target(doc, fragment); err != nil {
+			return nil, "", err
+		}
+		base = doc
+	}
+}
+
+// Resolve loads the document and inlines all references, see #RefResolver.ResolveObj().
+func (r *RefResolver) Resolve(uri string) (Obj, error) {
+	doc, err := r.Load(uri)
+	if err != nil {
+		return nil, err
+	}
+	return r.ResolveObj(doc, uri)
+}
+
+// ResolveObj returns a deep copy, in which all references are replaced by copies of their values. Other
+// members next to a $ref are merged into the referenced object. References which contain themselves
+// cannot be inlined and are reported as errors, use #RefResolver.Bundle() for recursive structures.
+func (r *RefResolver) ResolveObj(obj Obj, base string) (Obj, error) {
+	s := &refInliner{r: r, stack: map[string]bool{}, done: map[string]interface{}{}}
+	res, err := s.value(Path{}, base, obj)
+	if err != nil {
+		return nil, err
+	}
+	resObj, ok := toObj(res)
+	if !ok {
+		return nil, typeMismatch(res, "object")
+	}
+	return resObj, nil
+}
+
+type refInliner struct {
+	r     *RefResolver
+	stack map[string]bool
+	done  map[string]interface{} // inlined targets by uri
+}
+
+func (s *refInliner) value(p Path, base string, v interface{}) (interface{}, error) {
+	if obj, ref, ok := refOf(v); ok {
+		doc, fragment, err := refTarget(s.r.base(base, obj), ref)
+		if err != nil {
+			return nil, withPath(p, err)
+		}
+		key := doc + "#" + fragment
+		res, ok := s.done[key]
+		if !ok {
+			if s.stack[key] {
+				return nil, withPath(p, fmt.Errorf("cyclic $ref %s", key))
+			}
+			target, err := s.r.target(doc, fragment)
+			if err != nil {
+				return nil, withPath(p, err)
+			}
+			s.stack[key] = true
+			res, err = s.value(p, doc, target)
+			delete(s.stack, key)
+			if err != nil {
+				return nil, err
+			}
+			s.done[key] = res
+		}
+
+		res = cloneValue(res)
+		if resObj, ok := res.(Object); ok {
+			for _, k := range sortedKeys(obj) {
+				if k == "$ref" {
+					continue
+				}
+				member, err := s.value(p.Child(k), base, obj.Get(k))
+				if err != nil {
+					return nil, err
+				}
+				resObj[k] = member
+			}
+		}
+		return res, nil
+	}
+
+	if obj, ok := toObj(v); ok && !isNil(v) {
+		base = s.r.base(base, obj)
+		res := Object{}
+		for _, k := range sortedKeys(obj) {
+			member, err := s.value(p.Child(k), base, obj.Get(k))
+			if err != nil {
+				return nil, err
+			}
+			res[k] = member
+		}
+		return res, nil
+	}
+
+	if arr, ok := toArr(v); ok && !isNil(v) {
+		res := make(Array, arr.Size())
+		for i := range res {
+			elem, err := s.value(p.Child(i), base, arr.Get(i))
+			if err != nil {
+				return nil, err
+			}
+			res[i] = elem
+		}
+		return &res, nil
+	}
+
+	return cloneValue(v), nil
+}
+
+// Bundle loads the document and returns a copy, which contains all external values. References into the
+// document itself are kept. Referenced values of other documents are copied into the DefsKey member of the
+// root and their references are rewritten to point there, so that the result is self contained and
+// recursive structures are preserved. The $id and $anchor values of the copies are removed.
+func (r *RefResolver) Bundle(uri string) (Obj, error) {
+	doc, err := r.Load(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	defsKey := r.DefsKey
+	if defsKey == "" {
+		defsKey = "$defs"
+	}
+	s := &refBundler{r: r, root: uri, rootID: r.base(uri, doc), defsKey: defsKey, names: map[string]string{}, used: map[string]bool{}, defs: Object{}}
+	if existing, ok := toObj(doc.Get(defsKey)); ok && !isNil(doc.Get(defsKey)) {
+		for _, k := range sortedKeys(existing) {
+			s.used[k] = true
+		}
+	}
+
+	v, err := s.value(Path{}, uri, doc)
+	if err != nil {
+		return nil, err
+	}
+	res := v.(Object)
+	if len(s.defs) == 0 {
+		return res, nil
+	}
+
+	defs, ok := res[defsKey].(Object)
+	if !ok {
+		if !isNil(res[defsKey]) {
+			return nil, withPath(Path{defsKey}, typeMismatch(res[defsKey], "object"))
+		}
+		defs = Object{}
+		res[defsKey] = defs
+	}
+	for k, v := range s.defs {
+		defs[k] = v
+	}
+	return res, nil
+}
+
+type refBundler struct {
+	r       *RefResolver
+	root    string
+	rootID  string // the base uri of the root, which differs if the root declares a $id
+	defsKey string
+	names   map[string]string // definition names by uri
+	used    map[string]bool
+	defs    Object
+}
+
+func (s *refBundler) value(p Path, base string, v interface{}) (interface{}, error) {
+	if obj, ok := toObj(v); ok && !isNil(v) {
+		base = s.r.base(base, obj)
+		res := Object{}
+		for _, k := range sortedKeys(obj) {
+			member, err := s.value(p.Child(k), base, obj.Get(k))
+			if err != nil {
+				return nil, err
+			}
+			res[k] = member
+		}
+		if ref, ok := obj.Get("$ref").(string); ok {
+			rewritten, err := s.ref(base, ref)
+			if err != nil {
+				return nil, withPath(p, err)
+			}
+			res["$ref"] = rewritten
+		}
+		return res, nil
+	}
+
+	if arr, ok := toArr(v); ok && !isNil(v) {
+		res := make(Array, arr.Size())
+		for i := range res {
+			elem, err := s.value(p.Child(i), base, arr.Get(i))
+			if err != nil {
+				return nil, err
+			}
+			res[i] = elem
+		}
+		return &res, nil
+	}
+
+	return cloneValue(v), nil
+}
+
+// ref returns the local reference for the target and copies external values into the definitions
+func (s *refBundler) ref(base, ref string) (string, error) {
+	doc, fragment, err := refTarget(base, ref)
+	if err != nil {
+		return "", err
+	}
+	if doc == s.root || doc == s.rootID {
+		return "#" + fragment, nil
+	}
+
+	key := doc + "#" + fragment
+	name, ok := s.names[key]
+	if !ok {
+		target, err := s.r.target(doc, fragment)
+		if err != nil {
+			return "", err
+		}
+		name = s.name(doc, fragment)
+		s.names[key] = name
+		bundled, err := s.value(Path{s.defsKey, name}, doc, target)
+		if err != nil {
+			return "", err
+		}
+		stripIDs(bundled)
+		s.defs[name] = bundled
+	}
+	return "#" + Path{s.defsKey, name}.Pointer(), nil
+}
+
+// stripIDs removes the $id and $anchor values of a bundled copy, because its references have been rewritten
+// to pointers into the root, which must not be resolved against another base uri.
+func stripIDs(v interface{}) {
+	switch t := v.(type) {
+	case Object:
+		for _, k := range []string{"$id", "$anchor"} {
+			if _, ok := t[k].(string); ok {
+				delete(t, k)
+			}
+		}
+		for _, member := range t {
+			stripIDs(member)
+		}
+	case *Array:
+		for _, elem := range *t {
+			stripIDs(elem)
+		}
+	}
+}
+
+// name derives a unique definition name from the last pointer token or the file name
+func (s *refBundler) name(doc, fragment string) string {
+	name := ""
+	if i := strings.LastIndexByte(fragment, '/'); i >= 0 {
+		name = pointerUnescaper.Replace(fragment[i+1:])
+	} else {
+		name = fragment
+	}
+	if name == "" {
+		name = strings.TrimSuffix(path.Base(doc), path.Ext(doc))
+	}
+
+	res := name
+	for i := 2; s.used[res]; i++ {
+		res = name + strconv.Itoa(i)
+	}
+	s.used[res] = true
+	return res
+}
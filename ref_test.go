@@ -0,0 +1,181 @@
+package xobj
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func refFS() fstest.MapFS {
+	return fstest.MapFS{
+		"api.json": {Data: []byte(`{
+			"paths": {"/pets": {"$ref": "paths/pets.json"}},
+			"definitions": {"Id": {"type": "integer"}}
+		}`)},
+		"paths/pets.json": {Data: []byte(`{
+			"get": {"items": {"$ref": "../models.json#/definitions/Pet"}, "description": "list"}
+		}`)},
+		"models.json": {Data: []byte(`{
+			"definitions": {
+				"Pet": {"properties": {"id": {"$ref": "api.json#/definitions/Id"}, "tags": {"$ref": "#/definitions/Tags"}}},
+				"Tags": {"type": "array", "items": {"type": "string"}},
+				"Node": {"properties": {"next": {"$ref": "#/definitions/Node"}}}
+			}
+		}`)},
+		"cycle.json": {Data: []byte(`{"node": {"$ref": "models.json#/definitions/Node"}}`)},
+	}
+}
+
+func TestRefResolver_Resolve(t *testing.T) {
+	r := NewRefResolver(refFS())
+	res, err := r.Resolve("api.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := parseObj(t, `{
+		"paths": {"/pets": {"get": {"description": "list", "items": {"properties": {
+			"id": {"type": "integer"},
+			"tags": {"type": "array", "items": {"type": "string"}}
+		}}}}},
+		"definitions": {"Id": {"type": "integer"}}
+	}`)
+	if !Equal(expected, res) {
+		t.Fatal("unexpected", res)
+	}
+
+	// documents are cached
+	doc, err := r.Load("paths/pets.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := r.Load("paths/pets.json")
+	if doc.(Object)["get"].(Object)["description"] != "list" || again == nil {
+		t.Fatal("unexpected", doc)
+	}
+
+	v, base, err := r.Follow("paths/pets.json", doc.Get("get").(Object)["items"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base != "models.json" || !Equal(v.(Obj), parseObj(t, `{"properties":{"id":{"$ref":"api.json#/definitions/Id"},"tags":{"$ref":"#/definitions/Tags"}}}`)) {
+		t.Fatal("unexpected", base, v)
+	}
+
+	_, err = r.Resolve("cycle.json")
+	if err == nil || !strings.Contains(err.Error(), "cyclic $ref models.json#/definitions/Node") {
+		t.Fatal("unexpected", err)
+	}
+
+	_, err = r.Resolve("missing.json")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestRefResolver_Bundle(t *testing.T) {
+	r := NewRefResolver(refFS())
+	res, err := r.Bundle("api.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := parseObj(t, `{
+		"paths": {"/pets": {"$ref": "#/$defs/pets"}},
+		"definitions": {"Id": {"type": "integer"}},
+		"$defs": {
+			"pets": {"get": {"description": "list", "items": {"$ref": "#/$defs/Pet"}}},
+			"Pet": {"properties": {"id": {"$ref": "#/definitions/Id"}, "tags": {"$ref": "#/$defs/Tags"}}},
+			"Tags": {"type": "array", "items": {"type": "string"}}
+		}
+	}`)
+	if !Equal(expected, res) {
+		t.Fatal("unexpected", res)
+	}
+
+	res, err = r.Bundle("cycle.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = parseObj(t, `{"node":{"$ref":"#/$defs/Node"},"$defs":{"Node":{"properties":{"next":{"$ref":"#/$defs/Node"}}}}}`)
+	if !Equal(expected, res) {
+		t.Fatal("unexpected", res)
+	}
+}
+
+func TestRefResolver_BundleRootID(t *testing.T) {
+	fsys := refFS()
+	fsys["schema.json"] = &fstest.MapFile{Data: []byte(`{
+		"$id": "https://example.com/schema.json",
+		"definitions": {"A": {"type": "string"}},
+		"a": {"$ref": "#/definitions/A"},
+		"b": {"$ref": "https://example.com/schema.json#/definitions/A"}
+	}`)}
+	res, err := NewRefResolver(fsys).Bundle("schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := parseObj(t, `{
+		"$id": "https://example.com/schema.json",
+		"definitions": {"A": {"type": "string"}},
+		"a": {"$ref": "#/definitions/A"},
+		"b": {"$ref": "#/definitions/A"}
+	}`)
+	if !Equal(expected, res) {
+		t.Fatal("unexpected", res)
+	}
+}
+
+func TestRefResolver_BundleExternalID(t *testing.T) {
+	fsys := refFS()
+	fsys["ext.json"] = &fstest.MapFile{Data: []byte(`{
+		"$id": "https://example.com/ext.json",
+		"definitions": {
+			"B": {"$anchor": "b", "properties": {"c": {"$ref": "#/definitions/C"}}},
+			"C": {"type": "string"}
+		}
+	}`)}
+	fsys["main.json"] = &fstest.MapFile{Data: []byte(`{"b": {"$ref": "ext.json#/definitions/B"}}`)}
+	res, err := NewRefResolver(fsys).Bundle("main.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := parseObj(t, `{
+		"b": {"$ref": "#/$defs/B"},
+		"$defs": {
+			"B": {"properties": {"c": {"$ref": "#/$defs/C"}}},
+			"C": {"type": "string"}
+		}
+	}`)
+	if !Equal(expected, res) {
+		t.Fatal("unexpected", res)
+	}
+}
+
+func TestRefResolver_IDs(t *testing.T) {
+	r := NewRefResolver(nil)
+	r.AddDocument("https://example.com/schemas/root.json", parseObj(t, `{
+		"$defs": {"address": {"$id": "address.json", "$anchor": "addr", "properties": {"zip": {"type": "string"}}}},
+		"home": {"$ref": "address.json"},
+		"work": {"$ref": "address.json#addr", "title": "work"}
+	}`))
+
+	res, err := r.Resolve("https://example.com/schemas/root.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Opt[string](res, "home.properties.zip.type", "") != "string" || Opt[string](res, "work.title", "") != "work" {
+		t.Fatal("unexpected", res)
+	}
+}
+
+func TestLookupPointer(t *testing.T) {
+	obj := parseObj(t, `{"a/b":{"m~n":[1,{"0":"x"}]}}`)
+	v, err := LookupPointer(obj, "/a~1b/m~0n/1/0")
+	if err != nil || v != "x" {
+		t.Fatal("unexpected", v, err)
+	}
+	if _, err := LookupPointer(obj, "/a~1b/m~0n/x"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,204 @@
+package xobj
+
+import (
+	"context"
+	"crypto/tls"
+	"mime"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Timing is the breakdown of the last attempt of a request. Phases which have not been necessary, e.g.
+// because a connection has been reused, are 0.
+type Timing struct {
+	// DNS is the time of the host name lookup
+	DNS time.Duration
+
+	// Connect is the time to establish the tcp connection
+	Connect time.Duration
+
+	// TLSHandshake is the time of the tls handshake
+	TLSHandshake time.Duration
+
+	// FirstByte is the time from the start of the attempt until the first byte of the response
+	FirstByte time.Duration
+
+	// Total is the time from the start of the attempt until the body has been read
+	Total time.Duration
+
+	// Reused is true, if the connection has been used before
+	Reused bool
+}
+
+// timingTrace records a Timing
+type timingTrace struct {
+	mutex                          sync.Mutex
+	start, dns, connect, handshake time.Time
+	timing                         Timing
+}
+
+func (t *timingTrace) record(fn func()) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	fn()
+}
+
+func (t *timingTrace) with(ctx context.Context) context.Context {
+	t.start = time.Now()
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			t.record(func() { t.dns = time.Now() })
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.record(func() { t.timing.DNS = time.Since(t.dns) })
+		},
+		ConnectStart: func(network, addr string) {
+			t.record(func() { t.connect = time.Now() })
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.record(func() { t.timing.Connect = time.Since(t.connect) })
+		},
+		TLSHandshakeStart: func() {
+			t.record(func() { t.handshake = time.Now() })
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			t.record(func() { t.timing.TLSHandshake = time.Since(t.handshake) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.record(func() { t.timing.Reused = info.Reused })
+		},
+		GotFirstResponseByte: func() {
+			t.record(func() { t.timing.FirstByte = time.Since(t.start) })
+		},
+	})
+}
+
+// done returns the timing, up to now
+func (t *timingTrace) done() Timing {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	res := t.timing
+	res.Total = time.Since(t.start)
+	return res
+}
+
+//=
+
+// A Response is the completely read result of a request. Unlike an *http.Response, it is not necessary to
+// close anything.
+type Response struct {
+	// StatusCode is e.g. 200 and Status is e.g. "200 OK"
+	StatusCode int
+	Status     string
+	Header     http.Header
+
+	// Body contains the raw bytes of the response
+	Body []byte
+
+	// Method and URL of the final request, after following all redirects
+	Method string
+	URL    *url.URL
+
+	// Redirects contains the URLs which have been redirected, in order
+	Redirects []*url.URL
+
+	// Attempts contains all tries of the request, see RetryPolicy
+	Attempts []*Attempt
+
+	// Timing of the last attempt
+	Timing Timing
+
+	once   sync.Once
+	obj    Obj
+	objErr error
+}
+
+// newResponse copies the metadata of the response and collects its redirects
+func newResponse(res *http.Response, data []byte) *Response {
+	r := &Response{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Header:     res.Header,
+		Body:       data,
+	}
+	if res.Request != nil {
+		r.Method = res.Request.Method
+		r.URL = res.Request.URL
+		for prev := res.Request.Response; prev != nil && prev.Request != nil; prev = prev.Request.Response {
+			r.Redirects = append([]*url.URL{prev.Request.URL}, r.Redirects...)
+		}
+	}
+	return r
+}
+
+// ContentType returns the media type of the Content-Type header without parameters, like application/json.
+// If the header is missing or invalid, the type is detected from the body.
+func (r *Response) ContentType() string {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil {
+		return mediaType
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(r.Body))
+	return mediaType
+}
+
+// Obj parses the body with the registered parsers of #Parse(). The body is parsed only once.
+func (r *Response) Obj() (Obj, error) {
+	r.once.Do(func() {
+		r.obj, r.objErr = Parse(r.Body)
+	})
+	return r.obj, r.objErr
+}
+
+// IsSuccess returns true for all 2xx status codes.
+func (r *Response) IsSuccess() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// EnsureSuccess returns an *HTTPError for all status codes other than 2xx.
+func (r *Response) EnsureSuccess() error {
+	if r.IsSuccess() {
+		return nil
+	}
+	res := &HTTPError{StatusCode: r.StatusCode, Status: r.Status, Method: r.Method, Body: r.Body}
+	if r.URL != nil {
+		res.URL = r.URL.String()
+	}
+	if obj, err := r.Obj(); err == nil {
+		res.Obj = obj
+	}
+	return res
+}
+
+// An HTTPError is returned by #Response.EnsureSuccess() for unsuccessful responses.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	URL        string
+
+	// Body is the raw body and Obj is the parsed body, if it has a registered format, otherwise nil
+	Body []byte
+	Obj  Obj
+}
+
+func (e *HTTPError) Error() string {
+	status := e.Status
+	if status == "" {
+		status = strconv.Itoa(e.StatusCode)
+	}
+	return e.Method + " " + e.URL + ": " + status
+}
+
+// IsClientError returns true for 4xx status codes.
+func (e *HTTPError) IsClientError() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
+// IsServerError returns true for 5xx status codes.
+func (e *HTTPError) IsServerError() bool {
+	return e.StatusCode >= 500
+}
@@ -0,0 +1,82 @@
+package xobj
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestRequestBuilder_Send(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, "/new", http.StatusFound)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write([]byte(`{"name":"new"}`))
+	})
+	mux.HandleFunc("/truncated", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"partial"`))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"not found"}`))
+	})
+	srv := startServer(t, mux)
+	newRequest := func(path string) *RequestBuilder {
+		return NewRequest().URL(srv.URL).Path(path)
+	}
+
+	res, err := newRequest("old").Send(http.MethodGet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 || res.Status != "200 OK" || res.ContentType() != "application/json" || string(res.Body) != `{"name":"new"}` {
+		t.Fatal("unexpected", res)
+	}
+	if res.URL.Path != "/new" || len(res.Redirects) != 1 || res.Redirects[0].Path != "/old" || res.Method != http.MethodGet {
+		t.Fatal("unexpected", res.URL, res.Redirects)
+	}
+	if len(res.Attempts) != 1 || res.Timing.Total <= 0 || res.Timing.FirstByte <= 0 || res.Timing.FirstByte > res.Timing.Total {
+		t.Fatal("unexpected", res.Attempts, res.Timing)
+	}
+	obj, err := res.Obj()
+	if err != nil || Opt[string](obj, "name", "") != "new" {
+		t.Fatal("unexpected", obj, err)
+	}
+	if err := res.EnsureSuccess(); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = newRequest("missing").Send(http.MethodGet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var httpErr *HTTPError
+	if err := res.EnsureSuccess(); !errors.As(err, &httpErr) {
+		t.Fatal("unexpected", err)
+	}
+	if !httpErr.IsClientError() || httpErr.IsServerError() || Opt[string](httpErr.Obj, "error", "") != "not found" {
+		t.Fatal("unexpected", httpErr)
+	}
+	if httpErr.Error() != "GET "+srv.URL+"/missing: 404 Not Found" {
+		t.Fatal("unexpected", httpErr.Error())
+	}
+	if res.ContentType() != "text/plain" {
+		t.Fatal("unexpected", res.ContentType())
+	}
+
+	// the status code is kept, if the body cannot be read
+	res, err = newRequest("truncated").RetryPolicy(NoRetry).Send(http.MethodGet)
+	if err == nil || res == nil || res.StatusCode != http.StatusAccepted || string(res.Body) != `{"partial"` {
+		t.Fatal("unexpected", res, err)
+	}
+	if _, status, err := newRequest("truncated").RetryPolicy(NoRetry).Get(); err == nil || status != http.StatusAccepted {
+		t.Fatal("unexpected", status, err)
+	}
+	if _, status, err := newRequest("truncated").RetryPolicy(NoRetry).GetBytes(); err == nil || status != http.StatusAccepted {
+		t.Fatal("unexpected", status, err)
+	}
+}
@@ -0,0 +1,184 @@
+package xobj
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// An Attempt describes a single try of a request. Either Response or Err is set. The body of a response
+// which has been retried is already closed.
+type Attempt struct {
+	// Number is 1 for the first attempt
+	Number   int
+	Request  *http.Request
+	Response *http.Response
+	Err      error
+
+	// Duration is the time until the response header has been received or the request failed
+	Duration time.Duration
+
+	// Delay is the time which has been waited before the next attempt
+	Delay time.Duration
+}
+
+// StatusCode returns the status of the response or 0, if the attempt has failed.
+func (a *Attempt) StatusCode() int {
+	if a.Response == nil {
+		return 0
+	}
+	return a.Response.StatusCode
+}
+
+// A RetryPolicy decides whether a request is repeated. It is invoked after each attempt and returns the delay
+// before the next attempt and true, or false to finish with the current response or error. Requests whose
+// body cannot be rewound are never repeated.
+type RetryPolicy interface {
+	Retry(attempt *Attempt) (time.Duration, bool)
+}
+
+// RetryPolicyFunc allows to use an ordinary function as a RetryPolicy.
+type RetryPolicyFunc func(attempt *Attempt) (time.Duration, bool)
+
+func (f RetryPolicyFunc) Retry(attempt *Attempt) (time.Duration, bool) {
+	return f(attempt)
+}
+
+// NoRetry sends each request only once.
+var NoRetry RetryPolicy = RetryPolicyFunc(func(attempt *Attempt) (time.Duration, bool) {
+	return 0, false
+})
+
+// BackoffPolicy is a RetryPolicy with exponential backoff. It retries network errors and the status codes
+// of RetryStatus for idempotent methods. A Retry-After header of the response replaces the computed delay.
+type BackoffPolicy struct {
+	// MaxAttempts is the maximum amount of attempts, including the first one
+	MaxAttempts int
+
+	// BaseDelay is the delay after the first attempt, which is doubled for each further attempt
+	BaseDelay time.Duration
+
+	// MaxDelay caps the computed delays and the Retry-After header, if not 0. Without it, computed delays
+	// are still capped at an hour, so that doubling cannot overflow.
+	MaxDelay time.Duration
+
+	// Jitter is the random fraction between 0 and 1 by which a computed delay is reduced, so that clients
+	// which have failed at the same time do not retry at the same time
+	Jitter float64
+
+	// RetryStatus decides which status codes are retried. The default retries 408, 429 and all 5xx codes
+	// except 501 and 505.
+	RetryStatus func(code int) bool
+
+	// NonIdempotent also retries methods like POST and PATCH, which may have been applied already
+	NonIdempotent bool
+}
+
+// DefaultRetryPolicy returns a BackoffPolicy with the given amount of attempts, which starts with 200ms and
+// waits at most 30s.
+func DefaultRetryPolicy(maxAttempts int) *BackoffPolicy {
+	return &BackoffPolicy{MaxAttempts: maxAttempts, BaseDelay: 200 * time.Millisecond, MaxDelay: 30 * time.Second, Jitter: 0.5}
+}
+
+// Retry implements RetryPolicy.
+func (p *BackoffPolicy) Retry(attempt *Attempt) (time.Duration, bool) {
+	if attempt.Number >= p.MaxAttempts {
+		return 0, false
+	}
+	if attempt.Request != nil && !p.NonIdempotent && !IsIdempotent(attempt.Request.Method) {
+		return 0, false
+	}
+
+	if attempt.Err != nil {
+		if errors.Is(attempt.Err, context.Canceled) {
+			return 0, false
+		}
+		return p.backoff(attempt.Number), true
+	}
+
+	retryStatus := p.RetryStatus
+	if retryStatus == nil {
+		retryStatus = IsRetryableStatus
+	}
+	if !retryStatus(attempt.StatusCode()) {
+		return 0, false
+	}
+
+	if d, ok := RetryAfter(attempt.Response, time.Now()); ok {
+		if p.MaxDelay > 0 && d > p.MaxDelay {
+			d = p.MaxDelay
+		}
+		return d, true
+	}
+	return p.backoff(attempt.Number), true
+}
+
+// maxBackoff caps computed delays, if the BackoffPolicy has no MaxDelay
+const maxBackoff = time.Hour
+
+func (p *BackoffPolicy) backoff(number int) time.Duration {
+	limit := p.MaxDelay
+	if limit <= 0 {
+		limit = maxBackoff
+	}
+	d := p.BaseDelay
+	for i := 1; i < number && d < limit; i++ {
+		d *= 2
+	}
+	if d > limit {
+		d = limit
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// IsIdempotent returns true for the methods, which can be repeated safely (RFC 7231).
+func IsIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// IsRetryableStatus returns true for 408, 429 and all 5xx codes except 501 and 505.
+func IsRetryableStatus(code int) bool {
+	switch {
+	case code == http.StatusRequestTimeout, code == http.StatusTooManyRequests:
+		return true
+	case code == http.StatusNotImplemented, code == http.StatusHTTPVersionNotSupported:
+		return false
+	}
+	return code >= 500 && code < 600
+}
+
+// RetryAfter returns the delay of the Retry-After header of the response, which is either in seconds or an
+// http date.
+func RetryAfter(res *http.Response, now time.Time) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := date.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
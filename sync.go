@@ -0,0 +1,828 @@
+package xobj
+
+import (
+	"sync"
+	"time"
+)
+
+// syncRoot is the shared state of a SyncObj and all wrappers which have been handed out by it
+type syncRoot struct {
+	mu sync.RWMutex
+	// data contains only Object and *Array containers, so that no accessor ever replaces a value
+	data Object
+	// shared is true, if data is referenced by a snapshot and must be copied before the next write
+	shared bool
+	// readOnly roots belong to snapshots, which never change and need no locking
+	readOnly bool
+}
+
+func (r *syncRoot) rlock() {
+	if !r.readOnly {
+		r.mu.RLock()
+	}
+}
+
+func (r *syncRoot) runlock() {
+	if !r.readOnly {
+		r.mu.RUnlock()
+	}
+}
+
+// lock acquires the write lock and performs the copy-on-write, if required
+func (r *syncRoot) lock() {
+	if r.readOnly {
+		panic("xobj: a snapshot is immutable")
+	}
+	r.mu.Lock()
+	if r.shared {
+		r.data = syncValue(r.data).(Object)
+		r.shared = false
+	}
+}
+
+func (r *syncRoot) unlock() {
+	r.mu.Unlock()
+}
+
+// syncValue creates a deep copy which contains only Object and *Array containers
+func syncValue(v interface{}) interface{} {
+	return (&lockedCopier{}).value(v)
+}
+
+// lockedCopier is like #syncValue() but resolves the wrappers of the root, whose write lock is held, by
+// their path. Their accessors would wait for the lock forever.
+type lockedCopier struct {
+	root      *syncRoot
+	resolving map[string]bool
+}
+
+func (c *lockedCopier) value(v interface{}) interface{} {
+	if path, ok := c.wrapperPath(v); ok {
+		key := path.String()
+		if c.resolving[key] {
+			logger.Info(Fields{"msg": "cyclic value dropped", "path": key})
+			return nil
+		}
+		resolved, ok := c.root.resolve(path)
+		if !ok {
+			return nil
+		}
+		c.resolving[key] = true
+		defer delete(c.resolving, key)
+		v = resolved
+	}
+
+	if obj, ok := toObj(v); ok {
+		res := Object{}
+		keys := obj.Keys()
+		for i := 0; i < keys.Size(); i++ {
+			k := keys.Get(i)
+			res[k] = c.value(obj.Get(k))
+		}
+		return res
+	}
+	if arr, ok := toArr(v); ok {
+		res := make(Array, arr.Size())
+		for i := range res {
+			res[i] = c.value(arr.Get(i))
+		}
+		return &res
+	}
+	if b, ok := v.([]byte); ok {
+		return copyBytes(b)
+	}
+	return v
+}
+
+// wrapperPath returns the path of a SyncObj or SyncArr of the locked root
+func (c *lockedCopier) wrapperPath(v interface{}) (Path, bool) {
+	if c.root == nil {
+		return nil, false
+	}
+	switch t := v.(type) {
+	case *SyncObj:
+		return t.path, t.root == c.root
+	case *SyncArr:
+		return t.path, t.root == c.root
+	}
+	return nil, false
+}
+
+// normalize replaces all foreign containers below the given Object or *Array with normalized copies
+func (c *lockedCopier) normalize(v interface{}) {
+	switch t := v.(type) {
+	case Object:
+		for k, e := range t {
+			t[k] = c.normalized(e)
+		}
+	case *Array:
+		for i, e := range *t {
+			(*t)[i] = c.normalized(e)
+		}
+	}
+}
+
+func (c *lockedCopier) normalized(v interface{}) interface{} {
+	switch v.(type) {
+	case Object, *Array:
+		c.normalize(v)
+		return v
+	}
+	return c.value(v)
+}
+
+// resolve follows the path through the normalized data
+func (r *syncRoot) resolve(path Path) (interface{}, bool) {
+	return resolvePath(r.data, path)
+}
+
+// resolvePath follows the path without modifying anything
+func resolvePath(v interface{}, path Path) (interface{}, bool) {
+	cur := v
+	for _, seg := range path {
+		v, err := child(cur, seg)
+		if err != nil {
+			return nil, false
+		}
+		cur = v
+	}
+	return cur, true
+}
+
+//=
+
+var _ Obj = (*SyncObj)(nil)
+
+// A SyncObj is an Obj which can be used concurrently. All nested Obj and Arr values which are returned
+// by it share the same lock and address their value by its path from the root, so they keep working
+// after a copy-on-write but point to a different value, if the document has been restructured. Values
+// which are put into a SyncObj are copied, so that there are no unsynchronized references. Accessing a
+// nested wrapper whose path has vanished behaves like an empty container, writes are dropped and logged.
+type SyncObj struct {
+	root *syncRoot
+	path Path
+}
+
+// NewSyncObj creates a SyncObj from a deep copy of the given object, which may be nil.
+func NewSyncObj(obj Obj) *SyncObj {
+	data := Object{}
+	if obj != nil {
+		data = syncValue(obj).(Object)
+	}
+	return &SyncObj{root: &syncRoot{data: data}}
+}
+
+// Snapshot returns an immutable version of the current state, which is cheap because the data is only
+// copied on the next write of this SyncObj. Writing to the snapshot panics, however OptObject and
+// OptArray return detached empty values, if there is no such field.
+func (o *SyncObj) Snapshot() *SyncObj {
+	if o.root.readOnly {
+		return o
+	}
+	// the write lock is only held shortly to mark the data, the copy is made by the next writer
+	o.root.mu.Lock()
+	defer o.root.mu.Unlock()
+	o.root.shared = true
+	snap := &syncRoot{data: o.root.data, readOnly: true}
+	return &SyncObj{root: snap, path: o.path}
+}
+
+// Update invokes the function with exclusive access, so that multiple changes become visible at once.
+// The given Obj must not be used after returning. The function must not call the accessors of this or any
+// other wrapper of the same document, because they would wait for the lock forever. However, such
+// wrappers may be put into the given Obj, their current value is copied.
+func (o *SyncObj) Update(fn func(obj Obj)) {
+	o.root.lock()
+	defer o.root.unlock()
+	obj := o.unsafeObj()
+	if obj == nil {
+		logger.Info(Fields{"msg": "update of vanished object dropped", "path": o.path.String()})
+		return
+	}
+	fn(obj)
+	(&lockedCopier{root: o.root, resolving: map[string]bool{}}).normalize(obj)
+}
+
+// unsafeObj resolves the current object, which requires a lock
+func (o *SyncObj) unsafeObj() Object {
+	v, ok := o.root.resolve(o.path)
+	if !ok {
+		return nil
+	}
+	obj, _ := v.(Object)
+	return obj
+}
+
+func (o *SyncObj) read(fn func(obj Object)) {
+	o.root.rlock()
+	defer o.root.runlock()
+	obj := o.unsafeObj()
+	if obj == nil {
+		obj = Object{}
+	}
+	fn(obj)
+}
+
+func (o *SyncObj) write(fn func(obj Object)) {
+	o.root.lock()
+	defer o.root.unlock()
+	obj := o.unsafeObj()
+	if obj == nil {
+		logger.Info(Fields{"msg": "write to vanished object dropped", "path": o.path.String()})
+		return
+	}
+	fn(obj)
+}
+
+// wrap returns nested containers as synchronized wrappers
+func (o *SyncObj) wrap(name string, v interface{}) interface{} {
+	switch t := v.(type) {
+	case Object:
+		return &SyncObj{root: o.root, path: o.path.Child(name)}
+	case *Array:
+		return &SyncArr{root: o.root, path: o.path.Child(name)}
+	case []byte:
+		return copyBytes(t)
+	}
+	return v
+}
+
+func (o *SyncObj) Keys() StrList {
+	var res StrList
+	o.read(func(obj Object) { res = obj.Keys() })
+	return res
+}
+
+func (o *SyncObj) Get(name string) interface{} {
+	var res interface{}
+	o.read(func(obj Object) { res = o.wrap(name, obj.Get(name)) })
+	return res
+}
+
+func (o *SyncObj) Put(name string, value interface{}) Obj {
+	value = syncValue(value)
+	o.write(func(obj Object) { obj.Put(name, value) })
+	return o
+}
+
+func (o *SyncObj) Remove(name string) Obj {
+	o.write(func(obj Object) { obj.Remove(name) })
+	return o
+}
+
+func (o *SyncObj) Has(name string) bool {
+	var res bool
+	o.read(func(obj Object) { res = obj.Has(name) })
+	return res
+}
+
+func (o *SyncObj) IsNull(name string) bool {
+	var res bool
+	o.read(func(obj Object) { res = obj.IsNull(name) })
+	return res
+}
+
+func (o *SyncObj) AsInt64(name string) (int64, error) {
+	var res int64
+	var err error
+	o.read(func(obj Object) { res, err = obj.AsInt64(name) })
+	return res, withPath(o.path, err)
+}
+
+func (o *SyncObj) OptInt64(name string, fallback int64) int64 {
+	v, err := o.AsInt64(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *SyncObj) PutInt64(name string, value int64) Obj {
+	o.write(func(obj Object) { obj.PutInt64(name, value) })
+	return o
+}
+
+func (o *SyncObj) AsBool(name string) (bool, error) {
+	var res bool
+	var err error
+	o.read(func(obj Object) { res, err = obj.AsBool(name) })
+	return res, withPath(o.path, err)
+}
+
+func (o *SyncObj) OptBool(name string, fallback bool) bool {
+	v, err := o.AsBool(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *SyncObj) PutBool(name string, value bool) Obj {
+	o.write(func(obj Object) { obj.PutBool(name, value) })
+	return o
+}
+
+func (o *SyncObj) AsFloat64(name string) (float64, error) {
+	var res float64
+	var err error
+	o.read(func(obj Object) { res, err = obj.AsFloat64(name) })
+	return res, withPath(o.path, err)
+}
+
+func (o *SyncObj) OptFloat64(name string, fallback float64) float64 {
+	v, err := o.AsFloat64(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *SyncObj) PutFloat64(name string, value float64) Obj {
+	o.write(func(obj Object) { obj.PutFloat64(name, value) })
+	return o
+}
+
+func (o *SyncObj) AsString(name string) (string, error) {
+	var res string
+	var err error
+	o.read(func(obj Object) { res, err = obj.AsString(name) })
+	return res, withPath(o.path, err)
+}
+
+func (o *SyncObj) OptString(name string, fallback string) string {
+	v, err := o.AsString(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *SyncObj) PutString(name string, value string) Obj {
+	o.write(func(obj Object) { obj.PutString(name, value) })
+	return o
+}
+
+func (o *SyncObj) AsTime(name string) (time.Time, error) {
+	var res time.Time
+	var err error
+	o.read(func(obj Object) { res, err = obj.AsTime(name) })
+	return res, withPath(o.path, err)
+}
+
+func (o *SyncObj) OptTime(name string, fallback time.Time) time.Time {
+	v, err := o.AsTime(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *SyncObj) PutTime(name string, value time.Time) Obj {
+	o.write(func(obj Object) { obj.PutTime(name, value) })
+	return o
+}
+
+func (o *SyncObj) AsDuration(name string) (time.Duration, error) {
+	var res time.Duration
+	var err error
+	o.read(func(obj Object) { res, err = obj.AsDuration(name) })
+	return res, withPath(o.path, err)
+}
+
+func (o *SyncObj) OptDuration(name string, fallback time.Duration) time.Duration {
+	v, err := o.AsDuration(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *SyncObj) PutDuration(name string, value time.Duration) Obj {
+	o.write(func(obj Object) { obj.PutDuration(name, value) })
+	return o
+}
+
+func (o *SyncObj) AsBytes(name string) ([]byte, error) {
+	var res []byte
+	var err error
+	o.read(func(obj Object) {
+		res, err = obj.AsBytes(name)
+	})
+	return res, withPath(o.path, err)
+}
+
+func (o *SyncObj) OptBytes(name string, fallback []byte) []byte {
+	v, err := o.AsBytes(name)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (o *SyncObj) PutBytes(name string, value []byte) Obj {
+	o.write(func(obj Object) { obj.PutBytes(name, value) })
+	return o
+}
+
+func (o *SyncObj) AsObject(name string) (Obj, error) {
+	var err error
+	o.read(func(obj Object) { _, err = obj.AsObject(name) })
+	if err != nil {
+		return nil, withPath(o.path, err)
+	}
+	return &SyncObj{root: o.root, path: o.path.Child(name)}, nil
+}
+
+func (o *SyncObj) OptObject(name string) Obj {
+	if v, err := o.AsObject(name); err == nil {
+		return v
+	}
+	if o.root.readOnly {
+		return NewObj()
+	}
+	o.write(func(obj Object) {
+		if _, err := obj.AsObject(name); err != nil {
+			obj.Put(name, Object{})
+		}
+	})
+	return &SyncObj{root: o.root, path: o.path.Child(name)}
+}
+
+func (o *SyncObj) PutObject(name string, value Obj) Obj {
+	v := syncValue(value)
+	o.write(func(obj Object) { obj.Put(name, v) })
+	return o
+}
+
+func (o *SyncObj) AsArray(name string) (Arr, error) {
+	var err error
+	o.read(func(obj Object) { _, err = obj.AsArray(name) })
+	if err != nil {
+		return nil, withPath(o.path, err)
+	}
+	return &SyncArr{root: o.root, path: o.path.Child(name)}, nil
+}
+
+func (o *SyncObj) OptArray(name string) Arr {
+	if v, err := o.AsArray(name); err == nil {
+		return v
+	}
+	if o.root.readOnly {
+		return NewArr()
+	}
+	o.write(func(obj Object) {
+		if _, err := obj.AsArray(name); err != nil {
+			obj.Put(name, &Array{})
+		}
+	})
+	return &SyncArr{root: o.root, path: o.path.Child(name)}
+}
+
+func (o *SyncObj) PutArray(name string, value Arr) Obj {
+	v := syncValue(value)
+	o.write(func(obj Object) { obj.Put(name, v) })
+	return o
+}
+
+func (o *SyncObj) String() string {
+	var res string
+	o.read(func(obj Object) { res = obj.String() })
+	return res
+}
+
+//==
+
+var _ Arr = (*SyncArr)(nil)
+
+// A SyncArr is the Arr counterpart of a SyncObj and can only be obtained from it.
+type SyncArr struct {
+	root *syncRoot
+	path Path
+}
+
+// unsafeArr resolves the current array, which requires a lock
+func (a *SyncArr) unsafeArr() *Array {
+	v, ok := a.root.resolve(a.path)
+	if !ok {
+		return nil
+	}
+	arr, _ := v.(*Array)
+	return arr
+}
+
+func (a *SyncArr) read(fn func(arr *Array)) {
+	a.root.rlock()
+	defer a.root.runlock()
+	arr := a.unsafeArr()
+	if arr == nil {
+		arr = &Array{}
+	}
+	fn(arr)
+}
+
+func (a *SyncArr) write(fn func(arr *Array)) {
+	a.root.lock()
+	defer a.root.unlock()
+	arr := a.unsafeArr()
+	if arr == nil {
+		logger.Info(Fields{"msg": "write to vanished array dropped", "path": a.path.String()})
+		return
+	}
+	fn(arr)
+}
+
+func (a *SyncArr) wrap(idx int, v interface{}) interface{} {
+	switch t := v.(type) {
+	case Object:
+		return &SyncObj{root: a.root, path: a.path.Child(idx)}
+	case *Array:
+		return &SyncArr{root: a.root, path: a.path.Child(idx)}
+	case []byte:
+		return copyBytes(t)
+	}
+	return v
+}
+
+func (a *SyncArr) Size() int {
+	var res int
+	a.read(func(arr *Array) { res = arr.Size() })
+	return res
+}
+
+func (a *SyncArr) Get(idx int) interface{} {
+	var res interface{}
+	a.read(func(arr *Array) { res = a.wrap(idx, arr.Get(idx)) })
+	return res
+}
+
+func (a *SyncArr) Put(idx int, value interface{}) Arr {
+	value = syncValue(value)
+	a.write(func(arr *Array) { arr.Put(idx, value) })
+	return a
+}
+
+func (a *SyncArr) IsNull(idx int) bool {
+	var res bool
+	a.read(func(arr *Array) { res = arr.IsNull(idx) })
+	return res
+}
+
+func (a *SyncArr) Remove(idx int) Arr {
+	a.write(func(arr *Array) { arr.Remove(idx) })
+	return a
+}
+
+func (a *SyncArr) AsInt64(idx int) (int64, error) {
+	var res int64
+	var err error
+	a.read(func(arr *Array) { res, err = arr.AsInt64(idx) })
+	return res, withPath(a.path, err)
+}
+
+func (a *SyncArr) OptInt64(idx int, fallback int64) int64 {
+	v, err := a.AsInt64(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *SyncArr) PutInt64(idx int, value int64) Arr {
+	a.write(func(arr *Array) { arr.PutInt64(idx, value) })
+	return a
+}
+
+func (a *SyncArr) AddInt64(value int64) Arr {
+	a.write(func(arr *Array) { arr.AddInt64(value) })
+	return a
+}
+
+func (a *SyncArr) AsBool(idx int) (bool, error) {
+	var res bool
+	var err error
+	a.read(func(arr *Array) { res, err = arr.AsBool(idx) })
+	return res, withPath(a.path, err)
+}
+
+func (a *SyncArr) OptBool(idx int, fallback bool) bool {
+	v, err := a.AsBool(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *SyncArr) PutBool(idx int, value bool) Arr {
+	a.write(func(arr *Array) { arr.PutBool(idx, value) })
+	return a
+}
+
+func (a *SyncArr) AddBool(value bool) Arr {
+	a.write(func(arr *Array) { arr.AddBool(value) })
+	return a
+}
+
+func (a *SyncArr) AsFloat64(idx int) (float64, error) {
+	var res float64
+	var err error
+	a.read(func(arr *Array) { res, err = arr.AsFloat64(idx) })
+	return res, withPath(a.path, err)
+}
+
+func (a *SyncArr) OptFloat64(idx int, fallback float64) float64 {
+	v, err := a.AsFloat64(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *SyncArr) PutFloat64(idx int, value float64) Arr {
+	a.write(func(arr *Array) { arr.PutFloat64(idx, value) })
+	return a
+}
+
+func (a *SyncArr) AddFloat64(value float64) Arr {
+	a.write(func(arr *Array) { arr.AddFloat64(value) })
+	return a
+}
+
+func (a *SyncArr) AsString(idx int) (string, error) {
+	var res string
+	var err error
+	a.read(func(arr *Array) { res, err = arr.AsString(idx) })
+	return res, withPath(a.path, err)
+}
+
+func (a *SyncArr) OptString(idx int, fallback string) string {
+	v, err := a.AsString(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *SyncArr) PutString(idx int, value string) Arr {
+	a.write(func(arr *Array) { arr.PutString(idx, value) })
+	return a
+}
+
+func (a *SyncArr) AddString(value string) Arr {
+	a.write(func(arr *Array) { arr.AddString(value) })
+	return a
+}
+
+func (a *SyncArr) AsTime(idx int) (time.Time, error) {
+	var res time.Time
+	var err error
+	a.read(func(arr *Array) { res, err = arr.AsTime(idx) })
+	return res, withPath(a.path, err)
+}
+
+func (a *SyncArr) OptTime(idx int, fallback time.Time) time.Time {
+	v, err := a.AsTime(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *SyncArr) PutTime(idx int, value time.Time) Arr {
+	a.write(func(arr *Array) { arr.PutTime(idx, value) })
+	return a
+}
+
+func (a *SyncArr) AddTime(value time.Time) Arr {
+	a.write(func(arr *Array) { arr.AddTime(value) })
+	return a
+}
+
+func (a *SyncArr) AsDuration(idx int) (time.Duration, error) {
+	var res time.Duration
+	var err error
+	a.read(func(arr *Array) { res, err = arr.AsDuration(idx) })
+	return res, withPath(a.path, err)
+}
+
+func (a *SyncArr) OptDuration(idx int, fallback time.Duration) time.Duration {
+	v, err := a.AsDuration(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *SyncArr) PutDuration(idx int, value time.Duration) Arr {
+	a.write(func(arr *Array) { arr.PutDuration(idx, value) })
+	return a
+}
+
+func (a *SyncArr) AddDuration(value time.Duration) Arr {
+	a.write(func(arr *Array) { arr.AddDuration(value) })
+	return a
+}
+
+func (a *SyncArr) AsBytes(idx int) ([]byte, error) {
+	var res []byte
+	var err error
+	a.read(func(arr *Array) {
+		res, err = arr.AsBytes(idx)
+	})
+	return res, withPath(a.path, err)
+}
+
+func (a *SyncArr) OptBytes(idx int, fallback []byte) []byte {
+	v, err := a.AsBytes(idx)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func (a *SyncArr) PutBytes(idx int, value []byte) Arr {
+	a.write(func(arr *Array) { arr.PutBytes(idx, value) })
+	return a
+}
+
+func (a *SyncArr) AddBytes(value []byte) Arr {
+	a.write(func(arr *Array) { arr.AddBytes(value) })
+	return a
+}
+
+func (a *SyncArr) AsObject(idx int) (Obj, error) {
+	var err error
+	a.read(func(arr *Array) { _, err = arr.AsObject(idx) })
+	if err != nil {
+		return nil, withPath(a.path, err)
+	}
+	return &SyncObj{root: a.root, path: a.path.Child(idx)}, nil
+}
+
+func (a *SyncArr) OptObject(idx int) Obj {
+	if v, err := a.AsObject(idx); err == nil {
+		return v
+	}
+	if a.root.readOnly {
+		return NewObj()
+	}
+	a.write(func(arr *Array) {
+		if _, err := arr.AsObject(idx); err != nil {
+			arr.Put(idx, Object{})
+		}
+	})
+	return &SyncObj{root: a.root, path: a.path.Child(idx)}
+}
+
+func (a *SyncArr) PutObject(idx int, value Obj) Arr {
+	v := syncValue(value)
+	a.write(func(arr *Array) { arr.Put(idx, v) })
+	return a
+}
+
+func (a *SyncArr) AddObject(value Obj) Arr {
+	v := syncValue(value)
+	a.write(func(arr *Array) { *arr = append(*arr, v) })
+	return a
+}
+
+func (a *SyncArr) AsArray(idx int) (Arr, error) {
+	var err error
+	a.read(func(arr *Array) { _, err = arr.AsArray(idx) })
+	if err != nil {
+		return nil, withPath(a.path, err)
+	}
+	return &SyncArr{root: a.root, path: a.path.Child(idx)}, nil
+}
+
+func (a *SyncArr) OptArray(idx int) Arr {
+	if v, err := a.AsArray(idx); err == nil {
+		return v
+	}
+	if a.root.readOnly {
+		return NewArr()
+	}
+	a.write(func(arr *Array) {
+		if _, err := arr.AsArray(idx); err != nil {
+			arr.Put(idx, &Array{})
+		}
+	})
+	return &SyncArr{root: a.root, path: a.path.Child(idx)}
+}
+
+func (a *SyncArr) PutArray(idx int, value Arr) Arr {
+	v := syncValue(value)
+	a.write(func(arr *Array) { arr.Put(idx, v) })
+	return a
+}
+
+func (a *SyncArr) AddArray(value Arr) Arr {
+	v := syncValue(value)
+	a.write(func(arr *Array) { *arr = append(*arr, v) })
+	return a
+}
+
+func (a *SyncArr) String() string {
+	var res string
+	a.read(func(arr *Array) { res = arr.String() })
+	return res
+}
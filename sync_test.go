@@ -0,0 +1,98 @@
+package xobj
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSyncObj(t *testing.T) {
+	obj := NewSyncObj(NewObj().PutObject("db", NewObj().PutString("host", "localhost")))
+	db := obj.OptObject("db")
+	list := obj.OptArray("list")
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				db.PutInt64("port", int64(j))
+				list.AddString(strconv.Itoa(i))
+				obj.Snapshot()
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = db.OptString("host", "")
+				_ = obj.String()
+				_ = list.Size()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if list.Size() != 800 {
+		t.Fatal("unexpected", list.Size())
+	}
+}
+
+func TestSyncObj_Snapshot(t *testing.T) {
+	obj := NewSyncObj(nil)
+	obj.OptObject("db").PutString("host", "a")
+	snap := obj.Snapshot()
+
+	obj.OptObject("db").PutString("host", "b")
+	obj.Update(func(o Obj) {
+		o.Put("raw", map[string]interface{}{"list": []interface{}{1}})
+	})
+
+	if v := snap.OptObject("db").OptString("host", ""); v != "a" {
+		t.Fatal("unexpected", v)
+	}
+	if snap.Has("raw") || snap.OptArray("missing").Size() != 0 {
+		t.Fatal("snapshot has changed", snap.String())
+	}
+	if v := obj.OptObject("raw").OptArray("list").AddInt64(2).Size(); v != 2 {
+		t.Fatal("unexpected", v)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	snap.PutString("x", "y")
+}
+
+func TestSyncObj_UpdateSameRoot(t *testing.T) {
+	obj := NewSyncObj(NewObj().PutObject("db", NewObj().PutString("host", "localhost")))
+	db := obj.OptObject("db")
+	tags := obj.OptArray("tags")
+	tags.AddString("a")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		obj.Update(func(o Obj) {
+			o.Put("backup", db)
+			o.Put("nested", map[string]interface{}{"tags": tags})
+			o.Put("self", obj)
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock")
+	}
+
+	db.PutString("host", "example.com")
+	if Opt[string](obj, "backup.host", "") != "localhost" || Opt[string](obj, "nested.tags[0]", "") != "a" {
+		t.Fatal("unexpected", obj.String())
+	}
+	if Opt[string](obj, "self.db.host", "") != "localhost" || !obj.OptObject("self").IsNull("self") {
+		t.Fatal("unexpected", obj.String())
+	}
+}
@@ -0,0 +1,258 @@
+package xobj
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts contains all layouts which are tried in order to parse a string into a time.Time
+var timeLayouts = DefaultTimeLayouts()
+
+// timeLocation is used for layouts without zone information and for unix timestamps
+var timeLocation = time.UTC
+
+// DefaultTimeLayouts returns a new list of the layouts, which are used if nothing else has been configured.
+func DefaultTimeLayouts() []string {
+	return []string{
+		time.RFC3339Nano,
+		time.RFC3339,
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05.999999999Z07:00",
+		"2006-01-02 15:04:05Z07:00",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04",
+		"2006-01-02",
+		"2006/01/02",
+		"02.01.2006 15:04:05",
+		"02.01.2006",
+		time.RFC1123Z,
+		time.RFC1123,
+		time.RFC850,
+		time.RFC822Z,
+		time.RFC822,
+		time.UnixDate,
+		time.RubyDate,
+		time.ANSIC,
+	}
+}
+
+// SetTimeLayouts replaces the package level layouts, which are tried in order when converting strings
+// into a time. This is not thread safe, so ensure that you do that at #init() time.
+func SetTimeLayouts(layouts ...string) {
+	timeLayouts = append([]string(nil), layouts...)
+}
+
+// SetTimeLocation sets the package level location, which is applied to layouts without zone
+// information and to unix timestamps. The default is UTC. This is not thread safe, so ensure that you do
+// that at #init() time.
+func SetTimeLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	timeLocation = loc
+}
+
+// unixMillisThreshold is the absolute value from which on a number is interpreted as unix milliseconds
+// instead of seconds. 1e11 seconds are in the year 5138, so there is no practical overlap.
+const unixMillisThreshold = 1e11
+
+// fromUnix converts seconds or milliseconds (detected by magnitude) into a time
+func fromUnix(v float64) (time.Time, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return time.Time{}, fmt.Errorf("%v is not a valid timestamp", v)
+	}
+	if math.Abs(v) >= unixMillisThreshold {
+		v /= 1000
+	}
+	sec, frac := math.Modf(v)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))).In(timeLocation), nil
+}
+
+func asTime(v interface{}) (time.Time, error) {
+	var res time.Time
+	var err error
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t == nil {
+			return res, typeMismatch(v, "time")
+		}
+		return *t, nil
+	case int:
+		res, err = fromUnix(float64(t))
+	case int64:
+		if t >= unixMillisThreshold || t <= -unixMillisThreshold {
+			return time.Unix(0, t*int64(time.Millisecond)).In(timeLocation), nil
+		}
+		return time.Unix(t, 0).In(timeLocation), nil
+	case int32:
+		return time.Unix(int64(t), 0).In(timeLocation), nil
+	case uint32:
+		return time.Unix(int64(t), 0).In(timeLocation), nil
+	case float64:
+		res, err = fromUnix(t)
+	case float32:
+		res, err = fromUnix(float64(t))
+	case string:
+		res, err = parseTime(t)
+	default:
+		return res, typeMismatch(v, "time")
+	}
+	if err != nil {
+		return res, conversionFailed(v, "time", err)
+	}
+	return res, nil
+}
+
+// parseTime tries all configured layouts and finally a unix timestamp
+func parseTime(str string) (time.Time, error) {
+	str = strings.TrimSpace(str)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, str, timeLocation); err == nil {
+			return t, nil
+		}
+	}
+	if f, err := strconv.ParseFloat(str, 64); err == nil {
+		return fromUnix(f)
+	}
+	return time.Time{}, fmt.Errorf("'%s' does not match any time layout", str)
+}
+
+func asDuration(v interface{}) (time.Duration, error) {
+	var res time.Duration
+	var err error
+	switch t := v.(type) {
+	case time.Duration:
+		return t, nil
+	case string:
+		res, err = parseDuration(t)
+	case float64, float32, int, int64, int32, int16, int8, uint64, uint32, uint16, uint8:
+		// any other number is interpreted as seconds, which is what most payloads use
+		f, _ := asFloat64(v)
+		res, err = secondsToDuration(f)
+	default:
+		return res, typeMismatch(v, "duration")
+	}
+	if err != nil {
+		return res, conversionFailed(v, "duration", err)
+	}
+	return res, nil
+}
+
+func secondsToDuration(f float64) (time.Duration, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || math.Abs(f) > math.MaxInt64/float64(time.Second) {
+		return 0, fmt.Errorf("%v seconds are not a valid duration", f)
+	}
+	return time.Duration(math.Round(f * float64(time.Second))), nil
+}
+
+// parseDuration accepts go duration strings (1h30m), ISO 8601 durations (PT1H30M) and plain seconds
+func parseDuration(str string) (time.Duration, error) {
+	str = strings.TrimSpace(str)
+	if d, err := time.ParseDuration(str); err == nil {
+		return d, nil
+	}
+	if d, err := parseISODuration(str); err == nil {
+		return d, nil
+	}
+	if f, err := strconv.ParseFloat(str, 64); err == nil {
+		return secondsToDuration(f)
+	}
+	return 0, fmt.Errorf("'%s' is not a duration", str)
+}
+
+// parseISODuration parses the ISO 8601 format PnWnDTnHnMnS. Years and months are rejected,
+// because they have no fixed length.
+func parseISODuration(str string) (time.Duration, error) {
+	s := str
+	neg := false
+	if strings.HasPrefix(s, "-") {
+		neg = true
+		s = s[1:]
+	} else if strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	if len(s) < 2 || (s[0] != 'P' && s[0] != 'p') {
+		return 0, fmt.Errorf("'%s' is not an ISO 8601 duration", str)
+	}
+	s = strings.ToUpper(s[1:])
+
+	var total float64
+	inTime := false
+	num := ""
+	units := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9' || r == '.' || r == ',':
+			if r == ',' {
+				r = '.'
+			}
+			num += string(r)
+			continue
+		case r == 'T':
+			if inTime || num != "" {
+				return 0, fmt.Errorf("'%s' is not an ISO 8601 duration", str)
+			}
+			inTime = true
+			continue
+		}
+
+		if num == "" {
+			return 0, fmt.Errorf("'%s' is not an ISO 8601 duration", str)
+		}
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0, fmt.Errorf("'%s' is not an ISO 8601 duration: %v", str, err)
+		}
+		num = ""
+		units++
+
+		var unit time.Duration
+		switch {
+		case r == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case r == 'H' && inTime:
+			unit = time.Hour
+		case r == 'M' && inTime:
+			unit = time.Minute
+		case r == 'S' && inTime:
+			unit = time.Second
+		case (r == 'Y' || r == 'M') && !inTime:
+			return 0, fmt.Errorf("'%s' uses years or months, which have no fixed duration", str)
+		default:
+			return 0, fmt.Errorf("'%s' is not an ISO 8601 duration", str)
+		}
+		total += f * float64(unit)
+	}
+
+	if num != "" || units == 0 {
+		return 0, fmt.Errorf("'%s' is not an ISO 8601 duration", str)
+	}
+	if total > math.MaxInt64 {
+		return 0, fmt.Errorf("'%s' overflows a duration", str)
+	}
+	if neg {
+		total = -total
+	}
+	return time.Duration(math.Round(total)), nil
+}
+
+// formatTime is the representation used by all PutTime methods. It is a string, so that
+// the value stays a valid json primitive.
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// formatDuration is the representation used by all PutDuration methods
+func formatDuration(d time.Duration) string {
+	return d.String()
+}
@@ -0,0 +1,90 @@
+package xobj
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObject_AsTime(t *testing.T) {
+	obj, err := Parse([]byte(`{"rfc":"2019-03-04T10:11:12+01:00","date":"2019-03-04","german":"04.03.2019","sec":1551690672,"ms":1551690672000,"frac":1551690672.5,"broken":"yesterday"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := time.Date(2019, 3, 4, 9, 11, 12, 0, time.UTC)
+	for _, key := range []string{"rfc", "sec", "ms"} {
+		if v, err := obj.AsTime(key); err != nil || !v.Equal(expected) {
+			t.Fatal("unexpected", key, v, err)
+		}
+	}
+
+	if v, err := obj.AsTime("frac"); err != nil || !v.Equal(expected.Add(500*time.Millisecond)) {
+		t.Fatal("unexpected", v, err)
+	}
+
+	day := time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC)
+	for _, key := range []string{"date", "german"} {
+		if v, err := obj.AsTime(key); err != nil || !v.Equal(day) {
+			t.Fatal("unexpected", key, v, err)
+		}
+	}
+
+	if _, err := obj.AsTime("broken"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if v := obj.OptTime("missing", day); !v.Equal(day) {
+		t.Fatal("unexpected", v)
+	}
+
+	obj.PutTime("now", expected)
+	if v, err := obj.AsTime("now"); err != nil || !v.Equal(expected) {
+		t.Fatal("unexpected", v, err)
+	}
+}
+
+func TestSetTimeLocation(t *testing.T) {
+	loc := time.FixedZone("test", 2*60*60)
+	SetTimeLocation(loc)
+	defer SetTimeLocation(nil)
+
+	v, err := NewObj().PutString("date", "2019-03-04 10:00:00").AsTime("date")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Equal(time.Date(2019, 3, 4, 8, 0, 0, 0, time.UTC)) {
+		t.Fatal("unexpected", v)
+	}
+}
+
+func TestArray_AsDuration(t *testing.T) {
+	arr := NewArr().
+		AddString("1h30m").
+		AddString("PT1H30M").
+		AddString("P1DT2H").
+		AddFloat64(5400).
+		AddDuration(90 * time.Minute).
+		AddString("P1M").
+		AddBool(true)
+
+	for i := 0; i < 5; i++ {
+		if i == 2 {
+			continue
+		}
+		if v, err := arr.AsDuration(i); err != nil || v != 90*time.Minute {
+			t.Fatal("unexpected", i, v, err)
+		}
+	}
+
+	if v, err := arr.AsDuration(2); err != nil || v != 26*time.Hour {
+		t.Fatal("unexpected", v, err)
+	}
+
+	if _, err := arr.AsDuration(5); err == nil {
+		t.Fatal("expected error for months")
+	}
+
+	if v := arr.OptDuration(6, time.Second); v != time.Second {
+		t.Fatal("unexpected", v)
+	}
+}
@@ -0,0 +1,193 @@
+package xobj
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// URL sets the scheme, host, port, path and query from a complete url, like https://example.com/a?b=c.
+// The query parameters are added to the existing ones.
+func (r *RequestBuilder) URL(rawURL string) *RequestBuilder {
+	u, err := parseHTTPURL(rawURL)
+	if err != nil {
+		r.err = err
+		return r
+	}
+
+	r.scheme = u.Scheme
+	r.host = u.Hostname()
+	r.port = 0
+	if port := u.Port(); port != "" {
+		r.port, _ = strconv.Atoi(port)
+	}
+	r.path, r.rawPath = u.Path, ""
+	if u.RawPath != "" {
+		r.rawPath = u.RawPath
+	}
+	for k, v := range u.Query() {
+		r.query[k] = append(r.query[k], v...)
+	}
+	return r
+}
+
+// BaseURL sets the url to which the path is appended, e.g. https://example.com/api/v1. The host, port and
+// scheme of the builder take precedence, if set.
+func (r *RequestBuilder) BaseURL(rawURL string) *RequestBuilder {
+	u, err := parseHTTPURL(rawURL)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	r.base = u
+	return r
+}
+
+func parseHTTPURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme '%s' in %s", u.Scheme, rawURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host in %s", rawURL)
+	}
+	return u, nil
+}
+
+// PathParam sets the value of a placeholder in the path, like {id} in /users/{id}. The value is converted
+// like #ToString() does and escaped as a single segment, so it may contain slashes.
+func (r *RequestBuilder) PathParam(name string, value interface{}) *RequestBuilder {
+	if r.pathParams == nil {
+		r.pathParams = map[string]string{}
+	}
+	r.pathParams[name] = ToString(value)
+	return r
+}
+
+// QueryValues adds the key once for each value, like a=1&a=2.
+func (r *RequestBuilder) QueryValues(key string, values ...string) *RequestBuilder {
+	for _, v := range values {
+		r.query.Add(key, v)
+	}
+	return r
+}
+
+// QueryInt adds a number into the query part of the url.
+func (r *RequestBuilder) QueryInt(key string, value int64) *RequestBuilder {
+	return r.Query(key, strconv.FormatInt(value, 10))
+}
+
+// QueryFloat adds a number into the query part of the url.
+func (r *RequestBuilder) QueryFloat(key string, value float64) *RequestBuilder {
+	return r.Query(key, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// QueryBool adds true or false into the query part of the url.
+func (r *RequestBuilder) QueryBool(key string, value bool) *RequestBuilder {
+	return r.Query(key, strconv.FormatBool(value))
+}
+
+// QueryObj adds all members of the object into the query part of the url. Arrays are added as repeated keys,
+// null is skipped and other values are converted like #ToString() does.
+func (r *RequestBuilder) QueryObj(obj Obj) *RequestBuilder {
+	for _, k := range sortedKeys(obj) {
+		v := obj.Get(k)
+		if arr, ok := toArr(v); ok && !isNil(v) {
+			for i := 0; i < arr.Size(); i++ {
+				r.query.Add(k, ToString(arr.Get(i)))
+			}
+			continue
+		}
+		if !isNil(v) {
+			r.query.Add(k, ToString(v))
+		}
+	}
+	return r
+}
+
+// BuildURL returns the url of the request, as it would be sent.
+func (r *RequestBuilder) BuildURL() (*url.URL, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	u := &url.URL{}
+	if r.base != nil {
+		*u = *r.base
+	}
+	switch {
+	case r.scheme != "":
+		u.Scheme = r.scheme
+	case u.Scheme == "":
+		u.Scheme = "https"
+	}
+	if r.host != "" {
+		host := strings.TrimSuffix(strings.TrimPrefix(r.host, "["), "]")
+		switch {
+		case r.port != 0:
+			u.Host = net.JoinHostPort(host, strconv.Itoa(r.port))
+		case strings.Contains(host, ":"):
+			u.Host = "[" + host + "]"
+		default:
+			u.Host = host
+		}
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host")
+	}
+
+	p := r.rawPath
+	if p == "" {
+		var err error
+		if p, err = r.expandPath(); err != nil {
+			return nil, err
+		}
+	}
+	basePath := u.EscapedPath()
+	u.Path, u.RawPath = "", ""
+	joined := strings.TrimSuffix(basePath, "/") + "/" + strings.TrimPrefix(p, "/")
+	unescaped, err := url.PathUnescape(joined)
+	if err != nil {
+		return nil, err
+	}
+	u.Path, u.RawPath = unescaped, joined
+
+	query := u.Query()
+	for k, v := range r.query {
+		query[k] = append(query[k], v...)
+	}
+	u.RawQuery = query.Encode()
+	u.Fragment, u.RawFragment = "", ""
+	return u, nil
+}
+
+// expandPath escapes the path segments and replaces the placeholders
+func (r *RequestBuilder) expandPath() (string, error) {
+	segments := strings.Split(r.path, "/")
+	for i, seg := range segments {
+		sb := &strings.Builder{}
+		for seg != "" {
+			start := strings.IndexByte(seg, '{')
+			end := strings.IndexByte(seg, '}')
+			if start < 0 || end < start {
+				sb.WriteString(url.PathEscape(seg))
+				break
+			}
+			name := seg[start+1 : end]
+			value, ok := r.pathParams[name]
+			if !ok {
+				return "", fmt.Errorf("missing path parameter '%s'", name)
+			}
+			sb.WriteString(url.PathEscape(seg[:start]))
+			sb.WriteString(url.PathEscape(value))
+			seg = seg[end+1:]
+		}
+		segments[i] = sb.String()
+	}
+	return strings.Join(segments, "/"), nil
+}
@@ -0,0 +1,45 @@
+package xobj
+
+import (
+	"testing"
+)
+
+func TestRequestBuilder_BuildURL(t *testing.T) {
+	tests := []struct {
+		r        *RequestBuilder
+		expected string
+	}{
+		{NewRequest().Host("example.com").Path("a b", "c/d").Query("q", "x&y"), "https://example.com/a%20b/c/d?q=x%26y"},
+		{NewRequest().Http().Host("example.com").Port(8080).Path("/"), "http://example.com:8080/"},
+		{NewRequest().Host("::1").Port(443), "https://[::1]:443/"},
+		{NewRequest().Host("[fe80::1]").Path("x"), "https://[fe80::1]/x"},
+		{NewRequest().Host("example.com").Path("/users/{id}/files/{name}.json").PathParam("id", int64(42)).PathParam("name", "a/b"), "https://example.com/users/42/files/a%2Fb.json"},
+		{NewRequest().URL("http://localhost:9000/api/items?a=1#frag").Query("a", "2").QueryValues("b", "x", "y"), "http://localhost:9000/api/items?a=1&a=2&b=x&b=y"},
+		{NewRequest().URL("https://example.com/a%2Fb/c"), "https://example.com/a%2Fb/c"},
+		{NewRequest().BaseURL("https://example.com/api/v1/?key=k").Path("/users").QueryInt("page", 2).QueryBool("all", true).QueryFloat("f", 1.5), "https://example.com/api/v1/users?all=true&f=1.5&key=k&page=2"},
+		{NewRequest().BaseURL("https://example.com/api").Host("other.com").Path("x"), "https://other.com/api/x"},
+		{NewRequest().BaseURL("http://example.com/api").Host("other.com").Port(8080).Path("x"), "http://other.com:8080/api/x"},
+		{NewRequest().BaseURL("http://example.com/api").Https().Path("x"), "https://example.com/api/x"},
+		{NewRequest().Host("example.com").QueryObj(parseObj(t, `{"ids":[1,2],"name":"n","skip":null}`)), "https://example.com/?ids=1&ids=2&name=n"},
+	}
+	for _, test := range tests {
+		u, err := test.r.BuildURL()
+		if err != nil {
+			t.Fatal(test.expected, err)
+		}
+		if u.String() != test.expected {
+			t.Fatal("unexpected", u.String(), test.expected)
+		}
+	}
+
+	for _, r := range []*RequestBuilder{
+		NewRequest().Path("x"),
+		NewRequest().URL("ftp://example.com"),
+		NewRequest().URL("/relative"),
+		NewRequest().Host("example.com").Path("/users/{id}"),
+	} {
+		if _, err := r.BuildURL(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
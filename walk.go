@@ -0,0 +1,280 @@
+package xobj
+
+import (
+	"errors"
+	"sort"
+)
+
+// SkipSubtree can be returned by a Visitor or a transformation to avoid descending into the
+// children of the current value. The walk continues with the next sibling.
+var SkipSubtree = errors.New("xobj: skip subtree")
+
+// StopWalk can be returned by a Visitor or a transformation to end the walk immediately. Walk and
+// Transform return nil in this case.
+var StopWalk = errors.New("xobj: stop walk")
+
+// A Visitor is invoked by #Walk() for each value of a document.
+type Visitor interface {
+	// Visit is called with the path from the root, the containing Obj or Arr (nil for the root) and the
+	// value as it is stored, which may be a raw map[string]interface{} or []interface{} as well. Returning
+	// SkipSubtree or StopWalk controls the walk, any other error aborts it and is returned.
+	Visit(path Path, parent interface{}, value interface{}) error
+}
+
+// VisitorFunc is an adapter to use ordinary functions as a Visitor.
+type VisitorFunc func(path Path, parent interface{}, value interface{}) error
+
+// Visit calls f(path, parent, value)
+func (f VisitorFunc) Visit(path Path, parent interface{}, value interface{}) error {
+	return f(path, parent, value)
+}
+
+// Walk visits the given object and all nested values depth-first, in pre-order. Keys of objects are
+// visited in sorted order, to be deterministic. In contrast to the accessors, raw slices are never replaced.
+func Walk(obj Obj, visitor Visitor) error {
+	w := &walker{fn: func(c *Cursor) error {
+		return visitor.Visit(c.Path(), c.Parent(), c.Value())
+	}}
+	return w.run(obj)
+}
+
+// Transform is like #Walk() but allows the function to change the document using the Cursor. A replaced value
+// is walked instead of the original one, removed and inserted values are not walked. Raw slices are
+// written back into their parent as raw slices. Persistent containers cannot be modified in place, so a
+// persistent root returns an error and editing a value of a nested persistent container panics.
+func Transform(obj Obj, fn func(c *Cursor) error) error {
+	if isPersistent(obj) {
+		return immutable(obj)
+	}
+	w := &walker{fn: fn, mutable: true}
+	return w.run(obj)
+}
+
+// A Cursor describes the current position of a #Transform() and provides the editing operations.
+// A Cursor is only valid during the invocation of the function.
+type Cursor struct {
+	w       *walker
+	path    Path
+	parent  interface{}
+	value   interface{}
+	removed bool
+	before  []interface{}
+	after   []interface{}
+}
+
+// Path returns a copy of the path from the root to the current value
+func (c *Cursor) Path() Path {
+	return append(Path{}, c.path...)
+}
+
+// Parent returns the Obj or Arr which contains the current value or nil for the root
+func (c *Cursor) Parent() interface{} {
+	return c.parent
+}
+
+// Value returns the current value as stored in the parent
+func (c *Cursor) Value() interface{} {
+	return c.value
+}
+
+// Key returns the key of the current value, if the parent is an Obj
+func (c *Cursor) Key() (string, bool) {
+	if len(c.path) == 0 {
+		return "", false
+	}
+	key, ok := c.path[len(c.path)-1].(string)
+	return key, ok
+}
+
+// Index returns the index of the current value, if the parent is an Arr
+func (c *Cursor) Index() (int, bool) {
+	if len(c.path) == 0 {
+		return 0, false
+	}
+	idx, ok := c.path[len(c.path)-1].(int)
+	return idx, ok
+}
+
+// Replace exchanges the current value in the parent. Panics for the root.
+func (c *Cursor) Replace(value interface{}) {
+	c.checkEditable()
+	switch p := c.parent.(type) {
+	case Obj:
+		key, _ := c.Key()
+		p.Put(key, value)
+	case Arr:
+		idx, _ := c.Index()
+		p.Put(idx, value)
+	}
+	c.value = value
+}
+
+// Remove deletes the current value from its parent. Panics for the root.
+func (c *Cursor) Remove() {
+	c.checkEditable()
+	c.removed = true
+}
+
+// InsertBefore inserts the value in front of the current value. Panics if the parent is not an Arr.
+func (c *Cursor) InsertBefore(value interface{}) {
+	c.checkEditable()
+	if _, ok := c.parent.(Arr); !ok {
+		panic("xobj: InsertBefore requires an array as parent")
+	}
+	c.before = append(c.before, value)
+}
+
+// InsertAfter inserts the value after the current value. Panics if the parent is not an Arr.
+func (c *Cursor) InsertAfter(value interface{}) {
+	c.checkEditable()
+	if _, ok := c.parent.(Arr); !ok {
+		panic("xobj: InsertAfter requires an array as parent")
+	}
+	c.after = append(c.after, value)
+}
+
+func (c *Cursor) checkEditable() {
+	if !c.w.mutable {
+		panic("xobj: a walk is read-only, use Transform instead")
+	}
+	if c.parent == nil {
+		panic("xobj: the root cannot be edited")
+	}
+	if isPersistent(c.parent) {
+		panic("xobj: persistent containers cannot be edited in place")
+	}
+}
+
+type walker struct {
+	fn      func(c *Cursor) error
+	mutable bool
+	path    Path
+}
+
+func (w *walker) run(obj Obj) error {
+	c := &Cursor{w: w, value: obj}
+	err := w.visit(c)
+	if err == StopWalk || err == SkipSubtree {
+		return nil
+	}
+	return err
+}
+
+// visit invokes the function for the cursor and descends into the (possibly replaced) value
+func (w *walker) visit(c *Cursor) error {
+	if err := w.fn(c); err != nil {
+		return err
+	}
+	if c.removed {
+		return nil
+	}
+
+	prev := w.path
+	w.path = c.path
+	defer func() { w.path = prev }()
+
+	if obj, ok := toObj(c.value); ok {
+		return w.visitObj(obj)
+	}
+
+	if arr, ok := toArr(c.value); ok {
+		err := w.visitArr(arr)
+		if raw, isRaw := c.value.([]interface{}); isRaw && w.mutable && c.parent != nil {
+			// write back the slice, because its length may have changed
+			updated := []interface{}(*arr.(*Array))
+			if len(updated) != len(raw) || (len(raw) > 0 && &updated[0] != &raw[0]) {
+				c.replaceSilently(updated)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *Cursor) replaceSilently(value interface{}) {
+	switch p := c.parent.(type) {
+	case Obj:
+		key, _ := c.Key()
+		p.Put(key, value)
+	case Arr:
+		idx, _ := c.Index()
+		p.Put(idx, value)
+	}
+}
+
+func (w *walker) visitObj(obj Obj) error {
+	keys := sortedKeys(obj)
+	for _, k := range keys {
+		if !obj.Has(k) {
+			continue // removed by a previous transformation
+		}
+		c := &Cursor{w: w, path: w.path.Child(k), parent: obj, value: obj.Get(k)}
+		err := w.visit(c)
+		if c.removed {
+			obj.Remove(k)
+		}
+		if err == SkipSubtree {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *walker) visitArr(arr Arr) error {
+	for i := 0; i < arr.Size(); i++ {
+		c := &Cursor{w: w, path: w.path.Child(i), parent: arr, value: arr.Get(i)}
+		err := w.visit(c)
+
+		for _, v := range c.before {
+			insertAt(arr, i, v)
+			i++
+		}
+		if c.removed {
+			arr.Remove(i)
+			i--
+		}
+		for j, v := range c.after {
+			insertAt(arr, i+1+j, v)
+		}
+		i += len(c.after)
+
+		if err == SkipSubtree {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertAt inserts the value into any Arr implementation. The Arr contract has no generic add, so
+// a placeholder is appended and the tail is shifted.
+func insertAt(arr Arr, idx int, value interface{}) {
+	if a, ok := arr.(*Array); ok {
+		*a = append(*a, nil)
+		copy((*a)[idx+1:], (*a)[idx:])
+		(*a)[idx] = value
+		return
+	}
+
+	arr.AddString("")
+	for j := arr.Size() - 1; j > idx; j-- {
+		arr.Put(j, arr.Get(j-1))
+	}
+	arr.Put(idx, value)
+}
+
+// sortedKeys returns the keys of the object in ascending order
+func sortedKeys(obj Obj) []string {
+	keys := obj.Keys()
+	res := make([]string, keys.Size())
+	for i := range res {
+		res[i] = keys.Get(i)
+	}
+	sort.Strings(res)
+	return res
+}
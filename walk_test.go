@@ -0,0 +1,79 @@
+package xobj
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	obj, err := Parse([]byte(`{"b":[1,{"x":true},[2]],"a":"text","c":{"skip":{"deep":1}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	err = Walk(obj, VisitorFunc(func(path Path, parent interface{}, value interface{}) error {
+		paths = append(paths, path.String())
+		if len(path) == 1 && path[0] == "c" {
+			return SkipSubtree
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(paths, " ") != " a b b[0] b[1] b[1].x b[2] b[2][0] c" {
+		t.Fatal("unexpected", paths)
+	}
+
+	count := 0
+	err = Walk(obj, VisitorFunc(func(path Path, parent interface{}, value interface{}) error {
+		count++
+		if count == 3 {
+			return StopWalk
+		}
+		return nil
+	}))
+	if err != nil || count != 3 {
+		t.Fatal("unexpected", count, err)
+	}
+
+	if _, ok := obj.Get("b").([]interface{}); !ok {
+		t.Fatal("walk must not replace raw slices")
+	}
+}
+
+func TestTransform(t *testing.T) {
+	obj, err := Parse([]byte(`{"list":[1,"remove",3,{"secret":"x","name":"y"}],"secret":"z"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Transform(obj, func(c *Cursor) error {
+		if key, ok := c.Key(); ok && key == "secret" {
+			c.Remove()
+		}
+		if c.Value() == "remove" {
+			c.Remove()
+		}
+		if f, ok := c.Value().(float64); ok && f == 3 {
+			c.InsertBefore(2.0)
+			c.InsertAfter(4.0)
+		}
+		if key, ok := c.Key(); ok && key == "name" {
+			c.Replace(NewObj().PutString("first", "y"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if obj.String() != `{"list":[1,2,3,4,{"name":{"first":"y"}}]}` {
+		t.Fatal("unexpected", obj.String())
+	}
+	if _, ok := obj.Get("list").([]interface{}); !ok {
+		t.Fatal("expected a raw slice")
+	}
+}
@@ -0,0 +1,96 @@
+// Package xobjtest contains assertions for tests, which compare xobj documents structurally and report
+// the differences by path instead of printing two long json strings.
+package xobjtest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/worldiety/xobj"
+)
+
+// UpdateEnv is the name of the environment variable, which causes #AssertGolden() to rewrite the golden
+// files instead of comparing with them, e.g. UPDATE_GOLDEN=1 go test ./...
+const UpdateEnv = "UPDATE_GOLDEN"
+
+// GoldenDir is the directory of the golden files, relative to the package under test.
+var GoldenDir = "testdata"
+
+// Options are used for all reports of this package.
+var Options = xobj.DiffOptions{Context: 3}
+
+// AssertEqual reports an error with the differences, if the objects are not structurally equal (see
+// xobj.Equal) and returns false.
+func AssertEqual(t testing.TB, expected, actual xobj.Obj) bool {
+	t.Helper()
+	if xobj.Equal(expected, actual) {
+		return true
+	}
+	t.Errorf("objects are not equal:\n%s", xobj.DiffReport(expected, actual, Options))
+	return false
+}
+
+// AssertJSONEq parses the expected json and compares it like #AssertEqual() does.
+func AssertJSONEq(t testing.TB, expected string, actual xobj.Obj) bool {
+	t.Helper()
+	obj, err := xobj.Parse([]byte(expected))
+	if err != nil {
+		t.Errorf("invalid expected json: %v", err)
+		return false
+	}
+	return AssertEqual(t, obj, actual)
+}
+
+// AssertGolden compares the object with the golden file <GoldenDir>/<name>.golden.json. If the environment
+// variable UpdateEnv is set, the file is written instead. A missing file is reported as error otherwise.
+func AssertGolden(t testing.TB, name string, actual xobj.Obj) bool {
+	t.Helper()
+	fname := filepath.Join(GoldenDir, name+".golden.json")
+
+	data, err := os.ReadFile(fname)
+	if os.Getenv(UpdateEnv) != "" {
+		if err := writeGolden(fname, actual); err != nil {
+			t.Errorf("cannot write golden file: %v", err)
+			return false
+		}
+		return true
+	}
+	if os.IsNotExist(err) {
+		t.Errorf("golden file %s missing, run with %s=1", fname, UpdateEnv)
+		return false
+	}
+	if err != nil {
+		t.Errorf("cannot read golden file: %v", err)
+		return false
+	}
+
+	expected, err := xobj.Parse(data)
+	if err != nil {
+		t.Errorf("invalid golden file %s: %v", fname, err)
+		return false
+	}
+	if xobj.Equal(expected, actual) {
+		return true
+	}
+	opts := Options
+	opts.OldName = fname
+	t.Errorf("object differs from golden file, run with %s=1 to update:\n%s", UpdateEnv, xobj.DiffReport(expected, actual, opts))
+	return false
+}
+
+// writeGolden writes indented json with sorted keys, so that the files are diffable in version control
+func writeGolden(fname string, obj xobj.Obj) error {
+	buf := &bytes.Buffer{}
+	opts := xobj.DefaultEncodeOptions()
+	opts.Indent = "  "
+	if _, err := xobj.WriteTo(buf, obj, opts); err != nil {
+		return err
+	}
+	buf.WriteString("\n")
+	if err := os.MkdirAll(filepath.Dir(fname), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(fname, buf.Bytes(), 0644)
+}
@@ -0,0 +1,61 @@
+package xobjtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/worldiety/xobj"
+)
+
+// recorder captures the reported errors instead of failing
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertJSONEq(t *testing.T) {
+	r := &recorder{TB: t}
+	obj := xobj.NewObj().PutString("name", "a").PutInt64("n", 1)
+
+	if !AssertJSONEq(r, `{"n":1.0,"name":"a"}`, obj) || len(r.errors) != 0 {
+		t.Fatal("unexpected", r.errors)
+	}
+	if AssertJSONEq(r, `{"n":2,"name":"a"}`, obj) || !strings.Contains(r.errors[0], "~ n: 2 -> 1") {
+		t.Fatal("unexpected", r.errors)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	GoldenDir = t.TempDir()
+	defer func() { GoldenDir = "testdata" }()
+
+	r := &recorder{TB: t}
+	obj := xobj.NewObj().PutString("name", "a")
+	if AssertGolden(r, "doc", obj) || len(r.errors) != 1 || !strings.Contains(r.errors[0], "missing") {
+		t.Fatal("unexpected", r.errors)
+	}
+
+	t.Setenv(UpdateEnv, "1")
+	r = &recorder{TB: t}
+	if !AssertGolden(r, "doc", obj) || len(r.errors) != 0 {
+		t.Fatal("unexpected", r.errors)
+	}
+	data, err := os.ReadFile(filepath.Join(GoldenDir, "doc.golden.json"))
+	if err != nil || string(data) != "{\n  \"name\": \"a\"\n}\n" {
+		t.Fatal("unexpected", string(data), err)
+	}
+
+	t.Setenv(UpdateEnv, "")
+	if AssertGolden(r, "doc", obj.PutString("name", "b")) || len(r.errors) != 1 {
+		t.Fatal("unexpected", r.errors)
+	}
+}